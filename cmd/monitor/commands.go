@@ -0,0 +1,573 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/sirupsen/logrus"
+
+	"scale-helper-monitor/internal/config"
+	"scale-helper-monitor/internal/monitor"
+)
+
+// commonOptions holds the flags shared by every command
+type commonOptions struct {
+	configPath string
+	tokensPath string
+	chains     string
+	tags       string
+	cases      string
+	pairs      string
+	dryRun     bool
+}
+
+// addCommonFlags registers the config path and test case filter flags
+func addCommonFlags(fs *flag.FlagSet) *commonOptions {
+	opts := &commonOptions{}
+	fs.StringVar(&opts.configPath, "config", "", "Path to config.yaml (default: search ., ./configs, $HOME/.scale-helper-monitor, /etc/scale-helper-monitor)")
+	fs.StringVar(&opts.tokensPath, "tokens", "./tokens.json", "Path to tokens.json")
+	fs.StringVar(&opts.chains, "chain", "", "Comma-separated chain names to run")
+	fs.StringVar(&opts.tags, "tag", "", "Comma-separated test case tags to run")
+	fs.StringVar(&opts.cases, "case", "", "Comma-separated test case names or <chain>/<IN>-<OUT> keys to run")
+	fs.StringVar(&opts.pairs, "pair", "", "Comma-separated token pairs to run, e.g. WETH-USDC")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "Run everything without sending notifications")
+	return opts
+}
+
+// filter builds the test case filter from the flags
+func (o *commonOptions) filter() monitor.TestCaseFilter {
+	return monitor.TestCaseFilter{
+		Chains: splitList(o.chains),
+		Tags:   splitList(o.tags),
+		Cases:  splitList(o.cases),
+		Pairs:  splitList(o.pairs),
+	}
+}
+
+// loadConfig loads the configuration and narrows it down to the selected test cases
+func loadConfig(opts *commonOptions) (*config.Config, time.Duration, error) {
+	cfg, err := config.Load(opts.configPath, opts.tokensPath)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	filter := opts.filter()
+	cfg.TestCases = filter.Apply(cfg.TestCases, cfg.Tokens)
+	cfg.Chains = filter.ApplyToChains(cfg.Chains)
+	cfg.Monitoring.DryRun = opts.dryRun
+
+	// Parse timeout for clients
+	timeout, err := time.ParseDuration(cfg.Monitoring.Timeout)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid timeout duration: %w", err)
+	}
+
+	return cfg, timeout, nil
+}
+
+// newMonitor creates the monitoring service and its clients
+func newMonitor(cfg *config.Config, timeout time.Duration, logger *logrus.Logger) (*monitor.Monitor, error) {
+	// Create clients
+	kyberClient := cfg.GetKyberSwapClient(timeout, logger)
+	slackClient, err := cfg.GetSlackClient(timeout, logger)
+	if err != nil {
+		return nil, err
+	}
+	tenderlyClient := cfg.GetTenderlyClient(timeout)
+
+	return monitor.NewMonitor(
+		&cfg.Monitoring,
+		cfg.TestCases,
+		cfg.Tokens,
+		cfg.OnlyScaleDownDexs,
+		cfg.Sources,
+		cfg.Chains,
+		kyberClient,
+		slackClient,
+		tenderlyClient,
+		logger,
+	)
+}
+
+// runCommand runs monitoring once or continuously
+func runCommand(args []string, logger *logrus.Logger, runOnce bool) error {
+	name := "run"
+	if runOnce {
+		name = "once"
+	}
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	opts := addCommonFlags(fs)
+	reportJSON := fs.String("report-json", "", "Write all results as JSON to this file (once only)")
+	reportJUnit := fs.String("report-junit", "", "Write a JUnit XML report to this file (once only)")
+	reportMarkdown := fs.String("report-markdown", "", "Append a Markdown summary to this file, defaults to $GITHUB_STEP_SUMMARY (once only)")
+	fs.Parse(args)
+
+	cfg, timeout, err := loadConfig(opts)
+	if err != nil {
+		return err
+	}
+	if *reportJSON != "" {
+		cfg.Reports.JSONFile = *reportJSON
+	}
+	if *reportJUnit != "" {
+		cfg.Reports.JUnitFile = *reportJUnit
+	}
+	if *reportMarkdown != "" {
+		cfg.Reports.MarkdownFile = *reportMarkdown
+	}
+	if len(cfg.TestCases) == 0 {
+		return fmt.Errorf("no test cases match the selected filters")
+	}
+
+	if runOnce {
+		logger.Info("Starting Scale Helper Monitor (one-shot mode)")
+	} else {
+		logger.Info("Starting Scale Helper Monitor (continuous mode)")
+	}
+	if opts.dryRun {
+		logger.Info("Dry run enabled, notifications will not be sent")
+	}
+
+	monitorService, err := newMonitor(cfg, timeout, logger)
+	if err != nil {
+		return fmt.Errorf("failed to create monitor: %w", err)
+	}
+	defer monitorService.Close()
+
+	// Create context for graceful shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if runOnce {
+		// One-shot mode: run monitoring once and exit
+		logger.Info("Running monitoring once...")
+		run, err := monitorService.RunMonitoringOnce(ctx)
+		if err != nil {
+			return fmt.Errorf("monitoring failed: %w", err)
+		}
+
+		if err := cfg.Reports.WriteAll(run); err != nil {
+			logger.WithError(err).Error("Failed to write reports")
+		}
+
+		// Let CI status reflect monitor health
+		if err := cfg.Reports.ExitPolicy.Evaluate(run); err != nil {
+			return fmt.Errorf("exit policy violated: %w", err)
+		}
+		logger.Info("Monitoring completed successfully")
+		return nil
+	}
+
+	// Continuous mode: handle shutdown signals
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	// Start monitoring in a goroutine
+	monitorDone := make(chan error, 1)
+	go func() {
+		monitorDone <- monitorService.RunMonitoring(ctx)
+	}()
+
+	// Wait for shutdown signal or monitoring error
+	select {
+	case sig := <-sigChan:
+		logger.WithField("signal", sig).Info("Received shutdown signal")
+		cancel()
+
+		// Wait for monitoring to stop
+		err := <-monitorDone
+		if err != nil && err != context.Canceled {
+			logger.WithError(err).Error("Monitoring stopped with error")
+		}
+
+	case err := <-monitorDone:
+		if err != nil {
+			return fmt.Errorf("monitoring stopped with error: %w", err)
+		}
+	}
+
+	logger.Info("Scale Helper Monitor stopped")
+	return nil
+}
+
+// replayCommand replays stored results given a run ID, a result ID or a JSON result dump
+func replayCommand(args []string, logger *logrus.Logger) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	opts := addCommonFlags(fs)
+	latest := fs.Bool("latest", false, "Replay against the latest block instead of the stored block")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: scale-helper-monitor replay [flags] <run-id|result-id|result.json>")
+	}
+	target := fs.Arg(0)
+
+	cfg, timeout, err := loadConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Starting Scale Helper Monitor (replay mode)")
+	monitorService, err := newMonitor(cfg, timeout, logger)
+	if err != nil {
+		return fmt.Errorf("failed to create monitor: %w", err)
+	}
+	defer monitorService.Close()
+
+	var results []*monitor.Result
+	if _, statErr := os.Stat(target); statErr == nil {
+		results, err = monitor.LoadResultsFromFile(target)
+	} else {
+		results, err = monitorService.FindStoredResults(target)
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	for i, result := range results {
+		if i > 0 {
+			fmt.Println()
+		}
+		report, err := monitorService.Replay(ctx, result, !*latest)
+		if err != nil {
+			return fmt.Errorf("failed to replay %s: %w", result.ID, err)
+		}
+		report.Print(os.Stdout)
+	}
+
+	return nil
+}
+
+// trafficCommand replays the scale helper on calldata sampled from recent router swaps
+func trafficCommand(args []string, logger *logrus.Logger) error {
+	fs := flag.NewFlagSet("traffic", flag.ExitOnError)
+	opts := addCommonFlags(fs)
+	blocks := fs.Uint64("blocks", 0, "Blocks to scan back from the latest block (default: monitoring.traffic.blocks)")
+	fromBlock := fs.Uint64("from-block", 0, "First block of a fixed range to scan")
+	toBlock := fs.Uint64("to-block", 0, "Last block of a fixed range to scan (default: latest)")
+	samples := fs.Int("samples", 0, "Swaps to sample per chain (default: monitoring.traffic.max_samples)")
+	perDex := fs.Int("per-dex", 0, "Swaps to sample per dex signature (default: monitoring.traffic.max_per_dex)")
+	reportJSON := fs.String("report-json", "", "Write all results as JSON to this file")
+	reportJUnit := fs.String("report-junit", "", "Write a JUnit XML report to this file")
+	reportMarkdown := fs.String("report-markdown", "", "Append a Markdown summary to this file, defaults to $GITHUB_STEP_SUMMARY")
+	fs.Parse(args)
+
+	cfg, timeout, err := loadConfig(opts)
+	if err != nil {
+		return err
+	}
+	traffic := &cfg.Monitoring.Traffic
+	if *blocks > 0 {
+		traffic.Blocks = *blocks
+	}
+	if *fromBlock > 0 {
+		traffic.FromBlock = *fromBlock
+	}
+	if *toBlock > 0 {
+		traffic.ToBlock = *toBlock
+	}
+	if *samples > 0 {
+		traffic.MaxSamples = *samples
+	}
+	if *perDex > 0 {
+		traffic.MaxPerDex = *perDex
+	}
+	if *reportJSON != "" {
+		cfg.Reports.JSONFile = *reportJSON
+	}
+	if *reportJUnit != "" {
+		cfg.Reports.JUnitFile = *reportJUnit
+	}
+	if *reportMarkdown != "" {
+		cfg.Reports.MarkdownFile = *reportMarkdown
+	}
+
+	logger.Info("Starting Scale Helper Monitor (traffic replay mode)")
+	if opts.dryRun {
+		logger.Info("Dry run enabled, notifications will not be sent")
+	}
+
+	monitorService, err := newMonitor(cfg, timeout, logger)
+	if err != nil {
+		return fmt.Errorf("failed to create monitor: %w", err)
+	}
+	defer monitorService.Close()
+
+	run, err := monitorService.RunTrafficReplay(context.Background())
+	if err != nil {
+		return fmt.Errorf("traffic replay failed: %w", err)
+	}
+	logger.WithFields(logrus.Fields{
+		"samples":  run.Total,
+		"failures": run.Failures,
+		"errors":   run.Errors,
+	}).Info("Traffic replay completed")
+
+	if err := cfg.Reports.WriteAll(run); err != nil {
+		logger.WithError(err).Error("Failed to write reports")
+	}
+	if err := cfg.Reports.ExitPolicy.Evaluate(run); err != nil {
+		return fmt.Errorf("exit policy violated: %w", err)
+	}
+	return nil
+}
+
+// corpusCommand verifies the golden corpus against a helper or captures new entries from passing results
+func corpusCommand(args []string, logger *logrus.Logger) error {
+	const usage = "usage: scale-helper-monitor corpus verify|capture [flags]"
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	switch args[0] {
+	case "verify":
+		return corpusVerifyCommand(args[1:], logger)
+	case "capture":
+		return corpusCaptureCommand(args[1:], logger)
+	default:
+		return errors.New(usage)
+	}
+}
+
+// corpusVerifyCommand re-runs every corpus entry with eth_call and diffs the outputs
+func corpusVerifyCommand(args []string, logger *logrus.Logger) error {
+	fs := flag.NewFlagSet("corpus verify", flag.ExitOnError)
+	opts := addCommonFlags(fs)
+	file := fs.String("file", "", "Corpus file (default: monitoring.corpus_file)")
+	helper := fs.String("helper", "", "Scale helper address to verify (default: CONTRACT_ADDRESS)")
+	block := fs.Uint64("block", 0, "Block to run every entry at (default: the block each entry was captured at, the latest block for another helper)")
+	fs.Parse(args)
+
+	cfg, timeout, err := loadConfig(opts)
+	if err != nil {
+		return err
+	}
+	corpus, err := monitor.LoadCorpus(corpusFile(cfg, *file))
+	if err != nil {
+		return err
+	}
+
+	// Keep the entries of the selected chains
+	chains := make(map[string]bool)
+	for _, chain := range cfg.Chains {
+		chains[chain.Name] = true
+	}
+	var entries []monitor.CorpusEntry
+	for _, entry := range corpus.Entries {
+		if chains[entry.ChainName] {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no corpus entries for the selected chains")
+	}
+
+	monitorService, err := newMonitor(cfg, timeout, logger)
+	if err != nil {
+		return fmt.Errorf("failed to create monitor: %w", err)
+	}
+	defer monitorService.Close()
+
+	checks := monitorService.VerifyCorpus(context.Background(), entries, *helper, *block)
+	if failed := monitor.PrintCorpusChecks(os.Stdout, checks); failed > 0 {
+		return fmt.Errorf("%d of %d corpus entries diverge", failed, len(checks))
+	}
+	return nil
+}
+
+// corpusCaptureCommand adds the passing results of a run or a JSON result dump to the corpus
+func corpusCaptureCommand(args []string, logger *logrus.Logger) error {
+	fs := flag.NewFlagSet("corpus capture", flag.ExitOnError)
+	opts := addCommonFlags(fs)
+	file := fs.String("file", "", "Corpus file (default: monitoring.corpus_file)")
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		return fmt.Errorf("usage: scale-helper-monitor corpus capture [flags] [run-id|results.json]")
+	}
+	source := fs.Arg(0)
+
+	cfg, timeout, err := loadConfig(opts)
+	if err != nil {
+		return err
+	}
+	corpus, err := monitor.LoadCorpus(corpusFile(cfg, *file))
+	if err != nil {
+		return err
+	}
+
+	monitorService, err := newMonitor(cfg, timeout, logger)
+	if err != nil {
+		return fmt.Errorf("failed to create monitor: %w", err)
+	}
+	defer monitorService.Close()
+
+	var results []*monitor.Result
+	if _, statErr := os.Stat(source); source != "" && statErr == nil {
+		results, err = monitor.LoadResultsFromFile(source)
+	} else {
+		var run *monitor.RunRecord
+		run, err = monitorService.FindStoredRun(source)
+		if run != nil {
+			results = run.Results
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	entries := monitorService.CorpusEntries(results)
+	added := corpus.Add(entries...)
+	if err := corpus.Save(); err != nil {
+		return err
+	}
+	logger.WithFields(logrus.Fields{
+		"passing": len(entries),
+		"added":   added,
+		"total":   len(corpus.Entries),
+	}).Info("Captured corpus entries")
+	return nil
+}
+
+// corpusFile picks the corpus file from the flag, the config or the default
+func corpusFile(cfg *config.Config, file string) string {
+	if file != "" {
+		return file
+	}
+	if cfg.Monitoring.CorpusFile != "" {
+		return cfg.Monitoring.CorpusFile
+	}
+	return monitor.DefaultCorpusFile
+}
+
+// validateCommand checks the configuration without running any test case
+func validateCommand(args []string, logger *logrus.Logger) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	opts := addCommonFlags(fs)
+	fs.Parse(args)
+
+	cfg, _, err := loadConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return fmt.Errorf("configuration is invalid")
+	}
+
+	fmt.Printf("Configuration is valid: %d test cases on %d chains\n", len(cfg.TestCases), len(cfg.Chains))
+	return nil
+}
+
+// sourcesCommand handles "sources list"
+func sourcesCommand(args []string, logger *logrus.Logger) error {
+	if len(args) == 0 || args[0] != "list" {
+		return fmt.Errorf("usage: scale-helper-monitor sources list [flags]")
+	}
+
+	fs := flag.NewFlagSet("sources list", flag.ExitOnError)
+	opts := addCommonFlags(fs)
+	fs.Parse(args[1:])
+
+	cfg, _, err := loadConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	for _, chain := range cfg.Chains {
+		sources := cfg.Sources[chain.Name]
+		fmt.Printf("%s (%d sources)\n", chain.Name, len(sources))
+		for _, source := range sources {
+			fmt.Printf("  %s\n", source)
+		}
+	}
+	return nil
+}
+
+// tokensCommand handles "tokens discover-slot"
+func tokensCommand(args []string, logger *logrus.Logger) error {
+	if len(args) == 0 || args[0] != "discover-slot" {
+		return fmt.Errorf("usage: scale-helper-monitor tokens discover-slot --chain <chain> --token <address> [flags]")
+	}
+
+	fs := flag.NewFlagSet("tokens discover-slot", flag.ExitOnError)
+	opts := addCommonFlags(fs)
+	token := fs.String("token", "", "Token address")
+	holder := fs.String("holder", "0xdeAD00000000000000000000000000000000dEAd", "Address whose balance slot is computed")
+	spender := fs.String("spender", "", "Router address, also discovers the holder's allowance slot for it")
+	maxIndex := fs.Int64("max-index", 100, "Highest mapping index to probe")
+	fs.Parse(args[1:])
+
+	chainNames := splitList(opts.chains)
+	if len(chainNames) != 1 || *token == "" {
+		return fmt.Errorf("exactly one --chain and a --token are required")
+	}
+
+	cfg, timeout, err := loadConfig(opts)
+	if err != nil {
+		return err
+	}
+	if len(cfg.Chains) == 0 || len(cfg.Chains[0].RPCURLs) == 0 {
+		return fmt.Errorf("no RPC URL configured for chain %s", chainNames[0])
+	}
+
+	client, err := ethclient.Dial(cfg.Chains[0].RPCURLs[0])
+	if err != nil {
+		return fmt.Errorf("failed to connect to RPC endpoint: %w", err)
+	}
+	defer client.Close()
+
+	logger.WithFields(logrus.Fields{
+		"chain":  cfg.Chains[0].Name,
+		"token":  *token,
+		"holder": *holder,
+	}).Info("Probing balance mapping slots")
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Duration(*maxIndex+1))
+	defer cancel()
+
+	discovery, err := monitor.DiscoverBalanceSlot(ctx, client, *token, *holder, *maxIndex)
+	if err != nil {
+		return err
+	}
+
+	var result interface{} = discovery
+	if *spender != "" {
+		allowance, err := monitor.DiscoverAllowanceSlot(ctx, client, *token, *holder, *spender, *maxIndex)
+		if err != nil {
+			return err
+		}
+		result = map[string]*monitor.SlotDiscovery{"balance": discovery, "allowance": allowance}
+	}
+
+	output, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal slot discovery: %w", err)
+	}
+	fmt.Println(string(output))
+	return nil
+}
+
+// splitList splits a comma-separated flag value
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	var values []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
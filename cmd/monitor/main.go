@@ -1,19 +1,32 @@
 package main
 
 import (
-	"context"
+	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
+	"strings"
+	_ "time/tzdata" // Alert timezones work in minimal images without tzdata
 
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
-
-	"scale-helper-monitor/internal/config"
-	"scale-helper-monitor/internal/monitor"
 )
 
+const usage = `Usage: scale-helper-monitor <command> [flags]
+
+Commands:
+  run                   Run monitoring continuously (default)
+  once                  Run every test case once and exit
+  replay <id|file>      Replay a stored run/result ID or a JSON result dump
+  traffic               Scale calldata sampled from recent router swaps
+  corpus verify         Re-run the golden corpus against a helper and diff the outputs
+  corpus capture        Add passing results of a run to the golden corpus
+  validate              Validate config.yaml and tokens.json
+  sources list          List available liquidity sources per chain
+  tokens discover-slot  Find the balance storage slot of a token
+
+Run "scale-helper-monitor <command> -h" for the flags of a command.
+Without a command, RUN_ONCE=true selects "once".
+`
+
 func main() {
 	// Load .env file if it exists
 	if err := godotenv.Load(); err != nil {
@@ -23,12 +36,6 @@ func main() {
 		logrus.Info("Successfully loaded .env file")
 	}
 
-	var runOnce bool
-	// Check environment variable as alternative to flag
-	if os.Getenv("RUN_ONCE") == "true" {
-		runOnce = true
-	}
-
 	// Setup logger
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.TextFormatter{
@@ -36,92 +43,45 @@ func main() {
 	})
 	logger.SetLevel(logrus.InfoLevel)
 
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		logger.WithError(err).Fatal("Failed to load configuration")
-	}
-
-	if runOnce {
-		logger.Info("Starting Scale Helper Monitor (one-shot mode)")
-	} else {
-		logger.Info("Starting Scale Helper Monitor (continuous mode)")
-	}
-
-	// Parse timeout for clients
-	timeout, err := time.ParseDuration(cfg.Monitoring.Timeout)
-	if err != nil {
-		logger.WithError(err).Fatal("Invalid timeout duration")
+	// Pick the command, keeping RUN_ONCE for existing deployments
+	command := "run"
+	if os.Getenv("RUN_ONCE") == "true" {
+		command = "once"
 	}
-
-	// Create clients
-	kyberClient := cfg.GetKyberSwapClient(timeout, logger)
-	slackClient := cfg.GetSlackClient(timeout, logger)
-	tenderlyClient := cfg.GetTenderlyClient(timeout)
-
-	// Create monitor
-	monitorService, err := monitor.NewMonitor(
-		&cfg.Monitoring,
-		cfg.TestCases,
-		cfg.Tokens,
-		cfg.OnlyScaleDownDexs,
-		cfg.Sources,
-		cfg.Chains,
-		kyberClient,
-		slackClient,
-		tenderlyClient,
-		logger,
-	)
-	if err != nil {
-		logger.WithError(err).Fatal("Failed to create monitor")
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command = args[0]
+		args = args[1:]
 	}
-	defer monitorService.Close()
-
-	// Create context for graceful shutdown
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
 
-	if runOnce {
-		// One-shot mode: run monitoring once and exit
-		logger.Info("Running monitoring once...")
-		err := monitorService.RunMonitoringOnce(ctx)
-		if err != nil {
-			logger.WithError(err).Error("Monitoring failed")
-			os.Exit(1)
-		}
-		logger.Info("Monitoring completed successfully")
+	var err error
+	switch command {
+	case "run":
+		err = runCommand(args, logger, false)
+	case "once":
+		err = runCommand(args, logger, true)
+	case "replay":
+		err = replayCommand(args, logger)
+	case "traffic":
+		err = trafficCommand(args, logger)
+	case "corpus":
+		err = corpusCommand(args, logger)
+	case "validate":
+		err = validateCommand(args, logger)
+	case "sources":
+		err = sourcesCommand(args, logger)
+	case "tokens":
+		err = tokensCommand(args, logger)
+	case "help":
+		fmt.Print(usage)
 		return
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
 	}
 
-	// Continuous mode: original behavior with signals and loops
-	// Handle shutdown signals
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
-	// Start monitoring in a goroutine
-	monitorDone := make(chan error, 1)
-	go func() {
-		monitorDone <- monitorService.RunMonitoring(ctx)
-	}()
-
-	// Wait for shutdown signal or monitoring error
-	select {
-	case sig := <-sigChan:
-		logger.WithField("signal", sig).Info("Received shutdown signal")
-		cancel()
-
-		// Wait for monitoring to stop
-		err := <-monitorDone
-		if err != nil && err != context.Canceled {
-			logger.WithError(err).Error("Monitoring stopped with error")
-		}
-
-	case err := <-monitorDone:
-		if err != nil {
-			logger.WithError(err).Error("Monitoring stopped with error")
-			os.Exit(1)
-		}
+	if err != nil {
+		logger.WithError(err).Errorf("Command %s failed", command)
+		os.Exit(1)
 	}
-
-	logger.Info("Scale Helper Monitor stopped")
 }
@@ -0,0 +1,88 @@
+package chaintest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// SimulatedChainID is the chain ID of every go-ethereum simulated backend
+const SimulatedChainID = 1337
+
+// Backend is a go-ethereum simulated chain with a funded deployer. Calls run
+// against the latest block, which Deploy advances.
+type Backend struct {
+	*backends.SimulatedBackend
+	key      *ecdsa.PrivateKey
+	deployer common.Address
+}
+
+// NewBackend creates a simulated chain
+func NewBackend() (*Backend, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate deployer key: %v", err)
+	}
+	deployer := crypto.PubkeyToAddress(key.PublicKey)
+	balance := new(big.Int).Exp(big.NewInt(10), big.NewInt(24), nil)
+
+	alloc := core.GenesisAlloc{deployer: {Balance: balance}}
+	return &Backend{
+		SimulatedBackend: backends.NewSimulatedBackend(alloc, 30_000_000),
+		key:              key,
+		deployer:         deployer,
+	}, nil
+}
+
+// ChainID returns SimulatedChainID, the simulated backend has no eth_chainId
+func (b *Backend) ChainID(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(SimulatedChainID), nil
+}
+
+// Deploy deploys a contract with the given runtime code and mines its block
+func (b *Backend) Deploy(ctx context.Context, runtime []byte) (common.Address, error) {
+	nonce, err := b.PendingNonceAt(ctx, b.deployer)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to get deployer nonce: %v", err)
+	}
+	gasPrice, err := b.SuggestGasPrice(ctx)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to get gas price: %v", err)
+	}
+
+	tx := types.NewTx(&types.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: gasPrice,
+		Gas:      1_000_000,
+		Data:     deployCode(runtime),
+	})
+	signed, err := types.SignTx(tx, types.LatestSignerForChainID(big.NewInt(SimulatedChainID)), b.key)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to sign deployment: %v", err)
+	}
+	if err := b.SendTransaction(ctx, signed); err != nil {
+		return common.Address{}, fmt.Errorf("failed to send deployment: %v", err)
+	}
+	b.Commit()
+
+	receipt, err := b.TransactionReceipt(ctx, signed.Hash())
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to get deployment receipt: %v", err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return common.Address{}, fmt.Errorf("deployment reverted")
+	}
+	return receipt.ContractAddress, nil
+}
+
+// DeployEchoHelper deploys the EchoHelperCode mock scale helper
+func (b *Backend) DeployEchoHelper(ctx context.Context) (common.Address, error) {
+	return b.Deploy(ctx, EchoHelperCode)
+}
@@ -0,0 +1,169 @@
+// Package chaintest runs the monitor's chain calls in process, either on a
+// programmable Fake or on a go-ethereum simulated Backend executing real EVM code.
+package chaintest
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// DefaultGas is estimated for calls to contracts without a SetGas value
+const DefaultGas = 50000
+
+// CallHandler answers the eth_calls sent to one contract
+type CallHandler func(msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+
+// Fake is a chain whose contracts are Go functions. Calls to an address
+// without a handler return no data, like calls to an account without code.
+type Fake struct {
+	mu       sync.Mutex
+	chainID  *big.Int
+	head     uint64
+	handlers map[common.Address]CallHandler
+	gas      map[common.Address]uint64
+	err      error // Returned by every call while set, e.g. a node going down
+	calls    []ethereum.CallMsg
+}
+
+// NewFake creates a chain with the given ID whose latest block is head
+func NewFake(chainID int64, head uint64) *Fake {
+	return &Fake{
+		chainID:  big.NewInt(chainID),
+		head:     head,
+		handlers: make(map[common.Address]CallHandler),
+		gas:      make(map[common.Address]uint64),
+	}
+}
+
+// Handle routes the calls to contract to handler
+func (f *Fake) Handle(contract common.Address, handler CallHandler) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handlers[contract] = handler
+}
+
+// SetGas sets the gas estimated for calls to contract
+func (f *Fake) SetGas(contract common.Address, gas uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.gas[contract] = gas
+}
+
+// Fail makes every call return err, nil restores the chain
+func (f *Fake) Fail(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+// Calls returns the eth_calls received so far, gas estimations excluded
+func (f *Fake) Calls() []ethereum.CallMsg {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]ethereum.CallMsg(nil), f.calls...)
+}
+
+// ChainID returns the chain ID
+func (f *Fake) ChainID(ctx context.Context) (*big.Int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	return new(big.Int).Set(f.chainID), nil
+}
+
+// HeaderByNumber returns a header stamped now, the latest one for a nil number
+func (f *Fake) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	if number == nil {
+		number = new(big.Int).SetUint64(f.head)
+	} else if number.Uint64() > f.head {
+		return nil, ethereum.NotFound
+	}
+	return &types.Header{Number: new(big.Int).Set(number), Time: uint64(time.Now().Unix())}, nil
+}
+
+// CallContract runs the handler of the called contract
+func (f *Fake) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, msg)
+	f.mu.Unlock()
+	return f.run(msg, blockNumber)
+}
+
+// EstimateGas returns the gas set for the called contract, failing like a
+// node would when the call reverts
+func (f *Fake) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	return f.EstimateGasAt(ctx, msg, nil)
+}
+
+// EstimateGasAt is EstimateGas with the call run at a block
+func (f *Fake) EstimateGasAt(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) (uint64, error) {
+	if _, err := f.run(msg, blockNumber); err != nil {
+		return 0, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if msg.To != nil {
+		if gas, exists := f.gas[*msg.To]; exists {
+			return gas, nil
+		}
+	}
+	return DefaultGas, nil
+}
+
+func (f *Fake) run(msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	f.mu.Lock()
+	err := f.err
+	var handler CallHandler
+	if msg.To != nil {
+		handler = f.handlers[*msg.To]
+	}
+	f.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	if handler == nil {
+		return nil, nil
+	}
+	return handler(msg, blockNumber)
+}
+
+// RevertError is the error a node answers a reverting eth_call with
+type RevertError struct {
+	Data []byte
+}
+
+// Revert returns the error of a call reverting with data, e.g. an encoded custom error
+func Revert(data []byte) error {
+	return &RevertError{Data: data}
+}
+
+func (e *RevertError) Error() string {
+	return fmt.Sprintf("execution reverted: %s", hexutil.Encode(e.Data))
+}
+
+// ErrorCode returns the JSON-RPC error code of reverts
+func (e *RevertError) ErrorCode() int {
+	return 3
+}
+
+// ErrorData returns the hex encoded revert data
+func (e *RevertError) ErrorData() interface{} {
+	return hexutil.Encode(e.Data)
+}
@@ -0,0 +1,81 @@
+package chaintest
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// scaleHelperABI is the getScaledInputData function of the scale helper
+var scaleHelperABI = mustParseABI(`[{
+	"name": "getScaledInputData",
+	"type": "function",
+	"stateMutability": "view",
+	"inputs": [{"name": "inputData", "type": "bytes"}, {"name": "newAmount", "type": "uint256"}],
+	"outputs": [{"name": "isSuccess", "type": "bool"}, {"name": "data", "type": "bytes"}]
+}]`)
+
+func mustParseABI(definition string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// ScaleFunc computes the getScaledInputData result of a mock scale helper
+type ScaleFunc func(inputData []byte, newAmount *big.Int) (isSuccess bool, data []byte, err error)
+
+// ScaleHelper decodes getScaledInputData calls for scale and encodes its result
+func ScaleHelper(scale ScaleFunc) CallHandler {
+	return func(msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+		method, err := scaleHelperABI.MethodById(msg.Data)
+		if err != nil {
+			return nil, Revert(nil)
+		}
+		args, err := method.Inputs.Unpack(msg.Data[4:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid getScaledInputData arguments: %v", err)
+		}
+
+		isSuccess, data, err := scale(args[0].([]byte), args[1].(*big.Int))
+		if err != nil {
+			return nil, err
+		}
+		return method.Outputs.Pack(isSuccess, data)
+	}
+}
+
+// EchoHelperCode is the runtime code of a mock scale helper whose
+// getScaledInputData returns (true, inputData), and reverts without data
+// when newAmount is zero. The selector is not checked.
+var EchoHelperCode = common.FromHex(
+	"602435" + // CALLDATALOAD(0x24), newAmount
+		"600a57" + // JUMPI to 0x0a when newAmount is not zero
+		"600080fd" + // REVERT(0, 0)
+		"5b" + // JUMPDEST
+		"6001600052" + // MSTORE(0x00, true)
+		"6040602052" + // MSTORE(0x20, 0x40), offset of data
+		"6044360380" + // CALLDATASIZE - 0x44, length word and inputData, twice
+		"6044604037" + // CALLDATACOPY(0x40, 0x44, size)
+		"604001" + // size + 0x40
+		"6000f3", // RETURN(0x00, size + 0x40)
+)
+
+// deployCode returns the init code deploying runtime
+func deployCode(runtime []byte) []byte {
+	init := []byte{
+		0x61, byte(len(runtime) >> 8), byte(len(runtime)), // PUSH2 len(runtime)
+		0x80,       // DUP1
+		0x60, 0x0c, // PUSH1 0x0c, offset of runtime in the init code
+		0x60, 0x00, // PUSH1 0x00
+		0x39,       // CODECOPY(0x00, 0x0c, len(runtime))
+		0x60, 0x00, // PUSH1 0x00
+		0xf3, // RETURN(0x00, len(runtime))
+	}
+	return append(init, runtime...)
+}
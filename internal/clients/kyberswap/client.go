@@ -7,46 +7,94 @@ import (
 	"math/rand"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
+// DefaultEnvironment names the aggregator configured by APIBaseURL and ClientID
+const DefaultEnvironment = "production"
+
 // Config represents KyberSwap configuration
 type Config struct {
-	APIBaseURL string
-	ClientID   string
+	APIBaseURL   string
+	ClientID     string
+	Environments []Environment // Extra aggregator environments, e.g. staging
+}
+
+// Environment represents a named aggregator deployment
+type Environment struct {
+	Name       string `mapstructure:"name"`
+	APIBaseURL string `mapstructure:"api_base_url"`
+	ClientID   string `mapstructure:"client_id"` // Defaults to Config.ClientID
 }
 
 // Client handles communication with KyberSwap API
 type Client struct {
-	baseURL  string
-	clientID string
-	client   *http.Client
-	logger   *logrus.Logger
+	baseURL      string
+	clientID     string
+	client       *http.Client
+	logger       *logrus.Logger
+	environments map[string]*Client // Extra environments by name
 }
 
 // NewClient creates a new KyberSwap API client
 func NewClient(config Config, timeout time.Duration, logger *logrus.Logger) *Client {
-	return &Client{
-		baseURL:  config.APIBaseURL,
-		clientID: config.ClientID,
-		client: &http.Client{
-			Timeout: timeout,
-		},
-		logger: logger,
+	httpClient := &http.Client{
+		Timeout: timeout,
+	}
+
+	c := &Client{
+		baseURL:      config.APIBaseURL,
+		clientID:     config.ClientID,
+		client:       httpClient,
+		logger:       logger,
+		environments: make(map[string]*Client),
+	}
+	for _, env := range config.Environments {
+		clientID := env.ClientID
+		if clientID == "" {
+			clientID = config.ClientID
+		}
+		c.environments[env.Name] = &Client{
+			baseURL:  env.APIBaseURL,
+			clientID: clientID,
+			client:   httpClient,
+			logger:   logger,
+		}
 	}
+	return c
+}
+
+// Environment returns the client of a named aggregator environment. An empty
+// name or DefaultEnvironment returns the client itself.
+func (c *Client) Environment(name string) (*Client, error) {
+	if name == "" || name == DefaultEnvironment {
+		return c, nil
+	}
+	env, exists := c.environments[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown aggregator environment %s", name)
+	}
+	return env, nil
 }
 
 // GetRoute fetches a route from KyberSwap API
-func (c *Client) GetRoute(chainName string, tokenIn, tokenOut, amount string, availableSources []string, includedSources []string) (*KyberSwapRouteEncodedData, *KyberSwapRoute, error) {
+func (c *Client) GetRoute(chainName string, tokenIn, tokenOut, amount string, availableSources []string, includedSources []string, routeParams RouteParams) (*KyberSwapRouteEncodedData, *KyberSwapRoute, error) {
 	routeURL := fmt.Sprintf("%s/%s/api/v1/routes", c.baseURL, chainName)
 
 	params := url.Values{}
 	params.Add("tokenIn", tokenIn)
 	params.Add("tokenOut", tokenOut)
 	params.Add("amountIn", amount)
+	if fee := routeParams.ExtraFee; fee != nil {
+		params.Add("feeAmount", strconv.Itoa(fee.FeeBps))
+		params.Add("isInBps", "true")
+		params.Add("chargeFeeBy", fee.ChargeFeeBy)
+		params.Add("feeReceiver", fee.FeeReceiver)
+	}
 	if len(includedSources) > 0 {
 		// Check if we need to randomly pick sources
 		sourcesString := strings.Join(includedSources, ",")
@@ -125,12 +173,23 @@ func (c *Client) GetRoute(chainName string, tokenIn, tokenOut, amount string, av
 	routeBuildURL := fmt.Sprintf("%s/%s/api/v1/route/build", c.baseURL, chainName)
 
 	// Create the request body for route/build
+	recipient := routeParams.Recipient
+	if recipient == "" {
+		recipient = routeParams.Sender
+	}
 	buildRequest := map[string]interface{}{
-		"routeSummary":         apiResponse.Data.RouteSummary,
-		"sender":               "0xdeAD00000000000000000000000000000000dEAd",
-		"recipient":            "0xdeAD00000000000000000000000000000000dEAd",
-		"slippageTolerance":    5000,
-		"ignoreCappedSlippage": true,
+		"routeSummary":      apiResponse.Data.RouteSummary,
+		"sender":            routeParams.Sender,
+		"recipient":         recipient,
+		"slippageTolerance": defaultSlippageBps,
+	}
+	if routeParams.SlippageBps != nil {
+		buildRequest["slippageTolerance"] = *routeParams.SlippageBps
+	} else {
+		buildRequest["ignoreCappedSlippage"] = true
+	}
+	if routeParams.Permit != "" {
+		buildRequest["permit"] = routeParams.Permit
 	}
 
 	buildRequestJSON, err := json.Marshal(buildRequest)
@@ -1,5 +1,31 @@
 package kyberswap
 
+// Fee charging currencies of FeeParams.ChargeFeeBy
+const (
+	ChargeFeeByCurrencyIn  = "currency_in"
+	ChargeFeeByCurrencyOut = "currency_out"
+)
+
+// defaultSlippageBps is used when RouteParams.SlippageBps is nil, together
+// with ignoreCappedSlippage so the build never rejects the route
+const defaultSlippageBps = 5000
+
+// RouteParams represents the integrator settings of a route request
+type RouteParams struct {
+	Sender      string
+	Recipient   string     // Defaults to Sender
+	SlippageBps *int       // nil uses defaultSlippageBps, 0 is a zero tolerance
+	ExtraFee    *FeeParams // Partner fee, nil for none
+	Permit      string     // Encoded permit passed to route/build
+}
+
+// FeeParams represents the extraFee settings of a route request
+type FeeParams struct {
+	FeeReceiver string
+	FeeBps      int
+	ChargeFeeBy string // ChargeFeeByCurrencyIn or ChargeFeeByCurrencyOut
+}
+
 // KyberSwapRoute represents a route response from KyberSwap API
 type KyberSwapRoute struct {
 	TokenIn       string            `json:"tokenIn"`
@@ -0,0 +1,511 @@
+package rpcpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultMaxHeadAge is used when no head age limit is configured
+const DefaultMaxHeadAge = 2 * time.Minute
+
+// Pool spreads the calls of one chain over a list of RPC endpoints. Endpoints
+// are dialed on first use and redialed after a transport failure, and a call
+// that fails at the transport level is retried on the next endpoint.
+type Pool struct {
+	chainName  string
+	chainID    int64
+	maxHeadAge time.Duration
+	logger     *logrus.Logger
+
+	mu        sync.Mutex
+	endpoints []*endpoint
+	current   int    // Endpoint tried first
+	served    string // Endpoint that served the last successful call
+}
+
+type endpoint struct {
+	url        string
+	name       string // Host only, RPC URLs often carry API keys
+	client     *ethclient.Client
+	unhealthy  bool // Failed a call or a check, cleared by the next passing Check
+	verified   bool // Reported the expected chain ID
+	wrongChain bool // Reported another chain ID, never used again
+}
+
+// Health represents the outcome of the health check of one endpoint
+type Health struct {
+	Endpoint string `json:"endpoint"`
+	Healthy  bool   `json:"healthy"`
+	Head     uint64 `json:"head,omitempty"`
+	HeadAge  string `json:"head_age,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// New creates a pool over the RPC URLs of a chain, nothing is dialed yet
+func New(chainName string, chainID int64, urls []string, maxHeadAge time.Duration, logger *logrus.Logger) *Pool {
+	if maxHeadAge <= 0 {
+		maxHeadAge = DefaultMaxHeadAge
+	}
+	pool := &Pool{chainName: chainName, chainID: chainID, maxHeadAge: maxHeadAge, logger: logger}
+	for i, rawURL := range urls {
+		pool.endpoints = append(pool.endpoints, &endpoint{url: rawURL, name: endpointName(rawURL, i)})
+	}
+	return pool
+}
+
+// endpointName identifies an endpoint in logs and results without its path or credentials
+func endpointName(rawURL string, index int) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return fmt.Sprintf("rpc-%d", index+1)
+	}
+	return parsed.Host
+}
+
+// Served returns the endpoint that served the last successful call
+func (p *Pool) Served() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.served
+}
+
+// Check dials every endpoint and checks its chain ID and the age of its latest
+// block. The first healthy endpoint serves the next calls.
+func (p *Pool) Check(ctx context.Context) []Health {
+	healths := make([]Health, len(p.endpoints))
+	firstHealthy := -1
+	for i, ep := range p.endpoints {
+		health := p.checkEndpoint(ctx, ep)
+		healths[i] = health
+
+		p.mu.Lock()
+		ep.unhealthy = !health.Healthy
+		if health.Healthy && firstHealthy < 0 {
+			firstHealthy = i
+			p.current = i
+		}
+		p.mu.Unlock()
+	}
+	return healths
+}
+
+func (p *Pool) checkEndpoint(ctx context.Context, ep *endpoint) Health {
+	health := Health{Endpoint: ep.name}
+	client, err := p.dial(ctx, ep)
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		p.reset(ep)
+		health.Error = fmt.Sprintf("failed to get chain ID: %v", err)
+		return health
+	}
+	if err := p.checkChainID(ep, chainID); err != nil {
+		health.Error = err.Error()
+		return health
+	}
+
+	header, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		p.reset(ep)
+		health.Error = fmt.Sprintf("failed to get latest block: %v", err)
+		return health
+	}
+	age := time.Since(time.Unix(int64(header.Time), 0)).Truncate(time.Second)
+	health.Head = header.Number.Uint64()
+	health.HeadAge = age.String()
+	if age > p.maxHeadAge {
+		health.Error = fmt.Sprintf("latest block is %s old, limit %s", age, p.maxHeadAge)
+		return health
+	}
+
+	health.Healthy = true
+	return health
+}
+
+// dial returns the client of an endpoint, dialing it on first use
+func (p *Pool) dial(ctx context.Context, ep *endpoint) (*ethclient.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if ep.client != nil {
+		return ep.client, nil
+	}
+
+	client, err := ethclient.DialContext(ctx, ep.url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %s: %v", ep.name, err)
+	}
+	ep.client = client
+	return client, nil
+}
+
+// checkChainID records whether an endpoint serves the pool's chain. An endpoint
+// on another chain is excluded for good.
+func (p *Pool) checkChainID(ep *endpoint, chainID *big.Int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if chainID.Int64() != p.chainID {
+		ep.wrongChain = true
+		return fmt.Errorf("chain ID %s, expected %d", chainID, p.chainID)
+	}
+	ep.verified = true
+	return nil
+}
+
+// verify checks the chain ID of an endpoint the first time it is used
+func (p *Pool) verify(ctx context.Context, ep *endpoint, client *ethclient.Client) error {
+	p.mu.Lock()
+	verified := ep.verified
+	p.mu.Unlock()
+	if verified {
+		return nil
+	}
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		// Not wrapped, so that a node without eth_chainId is failed over
+		return fmt.Errorf("failed to get chain ID: %v", err)
+	}
+	return p.checkChainID(ep, chainID)
+}
+
+// reset drops the client of an endpoint so the next call dials it again
+func (p *Pool) reset(ep *endpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if ep.client != nil {
+		ep.client.Close()
+		ep.client = nil
+	}
+}
+
+// order lists the endpoints to try, healthy ones from the current endpoint on
+// first, then the unhealthy ones as a last resort. Endpoints on another chain
+// are never tried.
+func (p *Pool) order() []*endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var healthy, unhealthy []*endpoint
+	for i := range p.endpoints {
+		ep := p.endpoints[(p.current+i)%len(p.endpoints)]
+		if ep.wrongChain {
+			continue
+		}
+		if ep.unhealthy {
+			unhealthy = append(unhealthy, ep)
+		} else {
+			healthy = append(healthy, ep)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+// do runs call on the endpoints in order until one answers. Errors the node
+// answered with, such as reverts, are returned without failing over.
+func (p *Pool) do(ctx context.Context, call func(*ethclient.Client) error) error {
+	_, err := p.serve(ctx, call)
+	return err
+}
+
+// serve runs call like do and returns the endpoint that answered
+func (p *Pool) serve(ctx context.Context, call func(*ethclient.Client) error) (*endpoint, error) {
+	if len(p.endpoints) == 0 {
+		return nil, fmt.Errorf("no RPC URL configured for chain %s", p.chainName)
+	}
+
+	var errs []error
+	for _, ep := range p.order() {
+		err := p.try(ctx, ep, call)
+		if err == nil || !isTransportError(ctx, err) {
+			return ep, err
+		}
+
+		p.logger.WithError(err).WithFields(logrus.Fields{
+			"chain":    p.chainName,
+			"endpoint": ep.name,
+		}).Warn("RPC endpoint failed, trying the next one")
+		errs = append(errs, fmt.Errorf("%s: %w", ep.name, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no RPC endpoint of %s serves chain ID %d", p.chainName, p.chainID)
+	}
+	return nil, fmt.Errorf("all RPC endpoints of %s failed: %w", p.chainName, errors.Join(errs...))
+}
+
+// try runs call on one endpoint. An endpoint that does not answer is marked
+// unhealthy, only a passing Check clears it. The endpoint becomes the current
+// one when it answers while healthy.
+func (p *Pool) try(ctx context.Context, ep *endpoint, call func(*ethclient.Client) error) error {
+	client, err := p.dial(ctx, ep)
+	if err == nil {
+		err = p.verify(ctx, ep, client)
+	}
+	if err == nil {
+		err = call(client)
+		if err == nil || !isTransportError(ctx, err) {
+			p.mu.Lock()
+			p.served = ep.name
+			if !ep.unhealthy {
+				for i := range p.endpoints {
+					if p.endpoints[i] == ep {
+						p.current = i
+					}
+				}
+			}
+			p.mu.Unlock()
+			return err
+		}
+	}
+
+	p.reset(ep)
+	p.mu.Lock()
+	ep.unhealthy = true
+	p.mu.Unlock()
+	return err
+}
+
+// isTransportError tells whether an error means the endpoint did not answer.
+// JSON-RPC errors, missing results and cancellations are not.
+func isTransportError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, ethereum.NotFound) {
+		return false
+	}
+	var rpcErr rpc.Error
+	return !errors.As(err, &rpcErr)
+}
+
+// ChainID returns the chain ID reported by the node
+func (p *Pool) ChainID(ctx context.Context) (chainID *big.Int, err error) {
+	err = p.do(ctx, func(client *ethclient.Client) error {
+		chainID, err = client.ChainID(ctx)
+		return err
+	})
+	return chainID, err
+}
+
+// BlockNumber returns the latest block number
+func (p *Pool) BlockNumber(ctx context.Context) (number uint64, err error) {
+	err = p.do(ctx, func(client *ethclient.Client) error {
+		number, err = client.BlockNumber(ctx)
+		return err
+	})
+	return number, err
+}
+
+// HeaderByNumber returns a block header, the latest one for a nil number
+func (p *Pool) HeaderByNumber(ctx context.Context, number *big.Int) (header *types.Header, err error) {
+	err = p.do(ctx, func(client *ethclient.Client) error {
+		header, err = client.HeaderByNumber(ctx, number)
+		return err
+	})
+	return header, err
+}
+
+// FilterLogs returns the logs matching a filter query
+func (p *Pool) FilterLogs(ctx context.Context, query ethereum.FilterQuery) (logs []types.Log, err error) {
+	err = p.do(ctx, func(client *ethclient.Client) error {
+		logs, err = client.FilterLogs(ctx, query)
+		return err
+	})
+	return logs, err
+}
+
+// TransactionByHash returns a transaction and whether it is still pending
+func (p *Pool) TransactionByHash(ctx context.Context, txHash common.Hash) (tx *types.Transaction, isPending bool, err error) {
+	err = p.do(ctx, func(client *ethclient.Client) error {
+		tx, isPending, err = client.TransactionByHash(ctx, txHash)
+		return err
+	})
+	return tx, isPending, err
+}
+
+// TransactionReceipt returns the receipt of a mined transaction
+func (p *Pool) TransactionReceipt(ctx context.Context, txHash common.Hash) (receipt *types.Receipt, err error) {
+	err = p.do(ctx, func(client *ethclient.Client) error {
+		receipt, err = client.TransactionReceipt(ctx, txHash)
+		return err
+	})
+	return receipt, err
+}
+
+// CallContract executes an eth_call, at the latest block for a nil blockNumber
+func (p *Pool) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) (result []byte, err error) {
+	err = p.do(ctx, func(client *ethclient.Client) error {
+		result, err = client.CallContract(ctx, msg, blockNumber)
+		return err
+	})
+	return result, err
+}
+
+// EstimateGas estimates the gas of a call at the latest block
+func (p *Pool) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (gas uint64, err error) {
+	err = p.do(ctx, func(client *ethclient.Client) error {
+		gas, err = client.EstimateGas(ctx, msg)
+		return err
+	})
+	return gas, err
+}
+
+// EstimateGasAt estimates the gas of a call at a block, the latest one for a nil blockNumber
+func (p *Pool) EstimateGasAt(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) (gas uint64, err error) {
+	err = p.do(ctx, func(client *ethclient.Client) error {
+		gas, err = estimateGasAt(ctx, client, msg, blockNumber)
+		return err
+	})
+	return gas, err
+}
+
+// CodeAt returns the code of an account, at the latest block for a nil blockNumber
+func (p *Pool) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) (code []byte, err error) {
+	err = p.do(ctx, func(client *ethclient.Client) error {
+		code, err = client.CodeAt(ctx, account, blockNumber)
+		return err
+	})
+	return code, err
+}
+
+// StorageAt returns a storage slot of an account, at the latest block for a nil blockNumber
+func (p *Pool) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) (value []byte, err error) {
+	err = p.do(ctx, func(client *ethclient.Client) error {
+		value, err = client.StorageAt(ctx, account, key, blockNumber)
+		return err
+	})
+	return value, err
+}
+
+// CallContext runs a raw JSON-RPC call, e.g. eth_simulateV1
+func (p *Pool) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	return p.do(ctx, func(client *ethclient.Client) error {
+		return client.Client().CallContext(ctx, result, method, args...)
+	})
+}
+
+// Pinned sends every call to one endpoint of a pool, see Pool.Pin
+type Pinned struct {
+	pool *Pool
+	ep   *endpoint
+}
+
+// Pin fetches a block header, the latest one for a nil number, and returns it
+// with a client bound to the endpoint that served it, so the calls made at that
+// block are never failed over to an endpoint that may not have it yet
+func (p *Pool) Pin(ctx context.Context, number *big.Int) (*Pinned, *types.Header, error) {
+	var header *types.Header
+	ep, err := p.serve(ctx, func(client *ethclient.Client) error {
+		var err error
+		header, err = client.HeaderByNumber(ctx, number)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Pinned{pool: p, ep: ep}, header, nil
+}
+
+func (c *Pinned) do(ctx context.Context, call func(*ethclient.Client) error) error {
+	err := c.pool.try(ctx, c.ep, call)
+	if err != nil && isTransportError(ctx, err) {
+		return fmt.Errorf("pinned RPC endpoint %s of %s failed: %w", c.ep.name, c.pool.chainName, err)
+	}
+	return err
+}
+
+// Served returns the pinned endpoint
+func (c *Pinned) Served() string {
+	return c.ep.name
+}
+
+// ChainID returns the chain ID reported by the pinned endpoint
+func (c *Pinned) ChainID(ctx context.Context) (chainID *big.Int, err error) {
+	err = c.do(ctx, func(client *ethclient.Client) error {
+		chainID, err = client.ChainID(ctx)
+		return err
+	})
+	return chainID, err
+}
+
+// HeaderByNumber returns a block header, the latest one for a nil number
+func (c *Pinned) HeaderByNumber(ctx context.Context, number *big.Int) (header *types.Header, err error) {
+	err = c.do(ctx, func(client *ethclient.Client) error {
+		header, err = client.HeaderByNumber(ctx, number)
+		return err
+	})
+	return header, err
+}
+
+// CallContract executes an eth_call, at the latest block for a nil blockNumber
+func (c *Pinned) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) (result []byte, err error) {
+	err = c.do(ctx, func(client *ethclient.Client) error {
+		result, err = client.CallContract(ctx, msg, blockNumber)
+		return err
+	})
+	return result, err
+}
+
+// EstimateGas estimates the gas of a call at the latest block
+func (c *Pinned) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (gas uint64, err error) {
+	err = c.do(ctx, func(client *ethclient.Client) error {
+		gas, err = client.EstimateGas(ctx, msg)
+		return err
+	})
+	return gas, err
+}
+
+// EstimateGasAt estimates the gas of a call at a block, the latest one for a nil blockNumber
+func (c *Pinned) EstimateGasAt(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) (gas uint64, err error) {
+	err = c.do(ctx, func(client *ethclient.Client) error {
+		gas, err = estimateGasAt(ctx, client, msg, blockNumber)
+		return err
+	})
+	return gas, err
+}
+
+// estimateGasAt calls eth_estimateGas with a block argument, which ethclient does not pass
+func estimateGasAt(ctx context.Context, client *ethclient.Client, msg ethereum.CallMsg, blockNumber *big.Int) (uint64, error) {
+	arg := map[string]interface{}{"from": msg.From, "to": msg.To}
+	if len(msg.Data) > 0 {
+		arg["input"] = hexutil.Bytes(msg.Data)
+	}
+	if msg.Value != nil {
+		arg["value"] = (*hexutil.Big)(msg.Value)
+	}
+	if msg.Gas != 0 {
+		arg["gas"] = hexutil.Uint64(msg.Gas)
+	}
+	block := "latest"
+	if blockNumber != nil {
+		block = hexutil.EncodeBig(blockNumber)
+	}
+	var gas hexutil.Uint64
+	if err := client.Client().CallContext(ctx, &gas, "eth_estimateGas", arg, block); err != nil {
+		return 0, err
+	}
+	return uint64(gas), nil
+}
+
+// Close closes the dialed endpoints
+func (p *Pool) Close() {
+	for _, ep := range p.endpoints {
+		p.reset(ep)
+	}
+}
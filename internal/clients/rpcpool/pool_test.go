@@ -0,0 +1,64 @@
+package rpcpool
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/sirupsen/logrus"
+)
+
+// ethService answers eth_chainId and eth_blockNumber for one chain
+type ethService struct {
+	chainID uint64
+	head    uint64
+}
+
+func (s *ethService) ChainId() hexutil.Uint64     { return hexutil.Uint64(s.chainID) }
+func (s *ethService) BlockNumber() hexutil.Uint64 { return hexutil.Uint64(s.head) }
+
+func newNode(t *testing.T, chainID, head uint64) *httptest.Server {
+	t.Helper()
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", &ethService{chainID: chainID, head: head}); err != nil {
+		t.Fatal(err)
+	}
+	node := httptest.NewServer(server)
+	t.Cleanup(node.Close)
+	return node
+}
+
+func TestPoolNeverUsesWrongChain(t *testing.T) {
+	ctx := context.Background()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	wrong := newNode(t, 56, 900)
+	right := newNode(t, 1, 100)
+	pool := New("ethereum", 1, []string{wrong.URL, right.URL}, time.Minute, logger)
+	defer pool.Close()
+
+	for i := 0; i < 2; i++ {
+		head, err := pool.BlockNumber(ctx)
+		if err != nil {
+			t.Fatalf("BlockNumber: %v", err)
+		}
+		if head != 100 {
+			t.Fatalf("head %d served by %s, want 100 from the chain 1 node", head, pool.Served())
+		}
+	}
+
+	// With the right node down, the node on another chain is still not used
+	right.Close()
+	if head, err := pool.BlockNumber(ctx); err == nil {
+		t.Fatalf("head %d served by %s, want an error", head, pool.Served())
+	}
+	if _, err := pool.BlockNumber(ctx); err == nil || !strings.Contains(err.Error(), "failed") {
+		t.Fatalf("error %v, want the endpoint failure", err)
+	}
+}
@@ -0,0 +1,200 @@
+package rpcsim
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"scale-helper-monitor/internal/clients/tenderly"
+)
+
+// Name identifies the backend in results and reports
+const Name = "rpc"
+
+// Node runs raw JSON-RPC calls, e.g. an *rpc.Client
+type Node interface {
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+}
+
+// Client simulates Tenderly bundles with eth_simulateV1 on the chain RPC nodes.
+// It has no dashboard, traces or asset changes, only the call status and revert data.
+type Client struct {
+	nodes map[string]Node // Tenderly network ID -> RPC node
+}
+
+// NewClient creates a simulator over the RPC nodes keyed by Tenderly network ID
+func NewClient(nodes map[string]Node) *Client {
+	return &Client{nodes: nodes}
+}
+
+// Name returns the backend name
+func (c *Client) Name() string {
+	return Name
+}
+
+// simulatePayload represents the first eth_simulateV1 parameter
+type simulatePayload struct {
+	BlockStateCalls []blockStateCall `json:"blockStateCalls"`
+	Validation      bool             `json:"validation"`
+}
+
+type blockStateCall struct {
+	StateOverrides map[string]accountOverride `json:"stateOverrides,omitempty"`
+	Calls          []callRequest              `json:"calls"`
+}
+
+type accountOverride struct {
+	Balance   string            `json:"balance,omitempty"`
+	StateDiff map[string]string `json:"stateDiff,omitempty"`
+}
+
+type callRequest struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Input string `json:"input"`
+	Value string `json:"value,omitempty"`
+}
+
+// simulatedBlock represents one block of the eth_simulateV1 response
+type simulatedBlock struct {
+	Calls []struct {
+		Status     hexutil.Uint64 `json:"status"`
+		GasUsed    hexutil.Uint64 `json:"gasUsed"`
+		ReturnData string         `json:"returnData"`
+		Error      *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+			Data    string `json:"data"`
+		} `json:"error"`
+	} `json:"calls"`
+}
+
+// SimulateSwapBundle runs the bundle in a single simulated block and returns
+// the outcome of the swap
+func (c *Client) SimulateSwapBundle(ctx context.Context, bundleReq *tenderly.SimulationBundleRequest) (*tenderly.SwapSimulation, error) {
+	if len(bundleReq.Simulations) == 0 {
+		return nil, fmt.Errorf("empty simulation bundle")
+	}
+
+	networkID := bundleReq.Simulations[0].NetworkID
+	node, exists := c.nodes[networkID]
+	if !exists {
+		return nil, fmt.Errorf("no RPC node for network %s", networkID)
+	}
+
+	block := blockStateCall{StateOverrides: make(map[string]accountOverride)}
+	var blockNumber uint64
+	for _, sim := range bundleReq.Simulations {
+		if err := mergeStateObjects(block.StateOverrides, sim.StateObjects); err != nil {
+			return nil, err
+		}
+		value, err := toHexQuantity(sim.Value)
+		if err != nil {
+			return nil, err
+		}
+		block.Calls = append(block.Calls, callRequest{From: sim.From, To: sim.To, Input: sim.Input, Value: value})
+		blockNumber = sim.BlockNumber
+	}
+
+	blockTag := "latest"
+	if blockNumber > 0 {
+		blockTag = hexutil.EncodeUint64(blockNumber)
+	}
+
+	var blocks []simulatedBlock
+	payload := simulatePayload{BlockStateCalls: []blockStateCall{block}}
+	if err := node.CallContext(ctx, &blocks, "eth_simulateV1", payload, blockTag); err != nil {
+		return nil, fmt.Errorf("eth_simulateV1 failed: %w", err)
+	}
+	if len(blocks) != 1 || len(blocks[0].Calls) != len(block.Calls) {
+		return nil, fmt.Errorf("unexpected eth_simulateV1 response")
+	}
+
+	// The swap is always the last call of the bundle
+	swap := blocks[0].Calls[len(block.Calls)-1]
+	simulation := &tenderly.SwapSimulation{Success: swap.Status == 1, GasUsed: uint64(swap.GasUsed)}
+	if simulation.Success {
+		return simulation, nil
+	}
+
+	simulation.RevertData = swap.ReturnData
+	if swap.Error != nil {
+		simulation.ErrorMessage = swap.Error.Message
+		if swap.Error.Data != "" {
+			simulation.RevertData = swap.Error.Data
+		}
+	}
+	if simulation.ErrorMessage == "" {
+		simulation.ErrorMessage = "execution reverted"
+	}
+	return simulation, nil
+}
+
+// mergeStateObjects converts Tenderly state objects to eth_simulateV1 overrides
+func mergeStateObjects(overrides map[string]accountOverride, stateObjects map[string]interface{}) error {
+	for address, object := range stateObjects {
+		fields, ok := object.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("unsupported state object for %s", address)
+		}
+
+		override := overrides[address]
+		if balance, ok := fields["balance"].(string); ok {
+			override.Balance = balance
+		}
+		switch storage := fields["storage"].(type) {
+		case nil:
+		case map[string]string:
+			for slot, value := range storage {
+				override.setSlot(slot, value)
+			}
+		case map[string]interface{}:
+			for slot, value := range storage {
+				override.setSlot(slot, fmt.Sprint(value))
+			}
+		default:
+			return fmt.Errorf("unsupported storage override for %s", address)
+		}
+		overrides[address] = override
+	}
+	return nil
+}
+
+// setSlot adds a storage override, padding both slot and value to 32 bytes
+func (o *accountOverride) setSlot(slot, value string) {
+	if o.StateDiff == nil {
+		o.StateDiff = make(map[string]string)
+	}
+	o.StateDiff[padWord(slot)] = padWord(value)
+}
+
+func padWord(value string) string {
+	value = strings.TrimPrefix(strings.ToLower(value), "0x")
+	if len(value) < 64 {
+		value = strings.Repeat("0", 64-len(value)) + value
+	}
+	return "0x" + value
+}
+
+// toHexQuantity converts the decimal or hex transaction value to an RPC quantity
+func toHexQuantity(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	if strings.HasPrefix(value, "0x") {
+		amount, err := hexutil.DecodeBig(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid transaction value %s: %v", value, err)
+		}
+		return hexutil.EncodeBig(amount), nil
+	}
+
+	amount, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return "", fmt.Errorf("invalid transaction value %s", value)
+	}
+	return hexutil.EncodeBig(amount), nil
+}
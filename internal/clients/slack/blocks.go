@@ -0,0 +1,268 @@
+package slack
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"scale-helper-monitor/internal/clients/kyberswap"
+
+	goslack "github.com/slack-go/slack"
+)
+
+// renderAlertMessages renders the alert as Block Kit messages. The first message
+// carries the summary; per-failure sections follow and are split across as many
+// messages as needed to stay within Slack's block and size limits. It also
+// returns the index of the first failure of each message.
+func (c *Client) renderAlertMessages(failures []MonitoringResult, totalTestCases int) ([]*goslack.WebhookMessage, []int) {
+	title, summary := c.renderSummary(failures, totalTestCases)
+	if len(failures) == 0 {
+		return []*goslack.WebhookMessage{newWebhookMessage(title, summary)}, []int{0}
+	}
+
+	// One compact section per failure, linking to the simulations for details
+	var failureBlocks []goslack.Block
+	for i, result := range failures {
+		failureBlocks = append(failureBlocks, sectionBlock(c.formatFailureSection(i+1, result)))
+	}
+
+	return chunkBlocks(title, append(summary, goslack.NewDividerBlock()), failureBlocks)
+}
+
+// renderSummary renders the title and summary blocks of a run
+func (c *Client) renderSummary(failures []MonitoringResult, totalTestCases int) (string, []goslack.Block) {
+	data := SummaryData{
+		Time:           c.now(),
+		TotalTestCases: totalTestCases,
+		Failures:       len(failures),
+		SuccessRate:    100,
+	}
+	if len(failures) > 0 {
+		data.SuccessRate = float64(totalTestCases-len(failures)) / float64(totalTestCases) * 100
+		data.Chains = c.getUniqueChains(failures)
+		data.ChainDex = c.formatChainDexSummary(failures)
+		data.SizeBuckets = c.formatSizeBuckets(failures)
+	}
+
+	title := strings.TrimSpace(execute(c.templates.title, data))
+	return title, []goslack.Block{
+		headerBlock(title),
+		sectionBlock(execute(c.templates.summary, data)),
+	}
+}
+
+// chunkBlocks packs the summary and failure blocks into messages that respect
+// Slack's per-message block count and text size limits, and returns the index
+// of the first failure block of each message
+func chunkBlocks(title string, summary, failureBlocks []goslack.Block) ([]*goslack.WebhookMessage, []int) {
+	var messages []*goslack.WebhookMessage
+	firsts := []int{0}
+
+	current := append([]goslack.Block{}, summary...)
+	currentLength := blocksTextLength(summary)
+	for i, block := range failureBlocks {
+		length := blocksTextLength([]goslack.Block{block})
+		if len(current)+1 > maxBlocksPerMessage-1 || currentLength+length > maxMessageLength {
+			messages = append(messages, newWebhookMessage(title, current))
+			firsts = append(firsts, i)
+			current = nil
+			currentLength = 0
+		}
+		current = append(current, block)
+		currentLength += length
+	}
+	messages = append(messages, newWebhookMessage(title, current))
+
+	// Number the continuation messages so readers know more parts follow
+	if len(messages) > 1 {
+		for i, message := range messages {
+			part := goslack.NewContextBlock("", markdownText(fmt.Sprintf("Part %d/%d", i+1, len(messages))))
+			message.Blocks.BlockSet = append(message.Blocks.BlockSet, part)
+			if i > 0 {
+				message.Text = fmt.Sprintf("%s (part %d/%d)", title, i+1, len(messages))
+			}
+		}
+	}
+
+	return messages, firsts
+}
+
+// renderFallbackMessage renders a truncated plain-text alert used when the
+// Block Kit messages cannot be delivered, listing the failures from index first
+// on, the earlier ones having gone out already
+func (c *Client) renderFallbackMessage(failures []MonitoringResult, totalTestCases, first int) *goslack.WebhookMessage {
+	var b strings.Builder
+	if first == 0 {
+		fmt.Fprintf(&b, "🚨 Scale Helper Monitor Alert - %d of %d test cases failed (details truncated)\n", len(failures), totalTestCases)
+	} else {
+		fmt.Fprintf(&b, "🚨 Scale Helper Monitor Alert (continued) - failures %d-%d of %d (details truncated)\n", first+1, len(failures), len(failures))
+	}
+	remaining := failures[first:]
+	for i, result := range remaining {
+		if i == maxFallbackFailures {
+			fmt.Fprintf(&b, "…and %d more\n", len(remaining)-maxFallbackFailures)
+			break
+		}
+		fmt.Fprintf(&b, "• %s %s %s: %s\n",
+			result.GetChainName(), result.GetID(), result.GetSizeBucket(), truncate(result.GetError(), 150))
+	}
+
+	return &goslack.WebhookMessage{Text: truncate(b.String(), maxSectionTextLength)}
+}
+
+// formatFailureSection renders one failure as a compact section with links
+func (c *Client) formatFailureSection(index int, result MonitoringResult) string {
+	exchanges := routeExchanges(result.GetRoute())
+	transfers := result.GetTransfers()
+	if len(transfers) > maxTransferLines {
+		transfers = append(transfers[:maxTransferLines:maxTransferLines], fmt.Sprintf("…and %d more", len(transfers)-maxTransferLines))
+	}
+	return execute(c.templates.failure, FailureData{
+		Index:               index,
+		ID:                  result.GetID(),
+		ChainName:           result.GetChainName(),
+		TokenIn:             result.GetTokenIn(),
+		TokenOut:            result.GetTokenOut(),
+		Amount:              result.GetAmount(),
+		NewAmount:           result.GetNewAmount(),
+		SizeBucket:          result.GetSizeBucket(),
+		Environment:         result.GetEnvironment(),
+		TxHash:              result.GetTxHash(),
+		Scenario:            result.GetScenario(),
+		BlockNumber:         result.GetBlockNumber(),
+		RouteSteps:          len(result.GetRoute()),
+		Exchanges:           exchanges,
+		OriginalTenderlyURL: result.GetOriginalTenderlyURL(),
+		ScaledTenderlyURL:   result.GetScaledTenderlyURL(),
+		Error:               truncate(escapeText(result.GetError()), maxErrorLength),
+		RevertReason:        truncate(escapeText(result.GetRevertReason()), maxErrorLength),
+		RevertContract:      result.GetRevertContract(),
+		FailingFrame:        result.GetFailingFrame(),
+		Transfers:           transfers,
+	})
+}
+
+// formatChainDexSummary counts failures per chain and per dex on the route
+func (c *Client) formatChainDexSummary(failures []MonitoringResult) string {
+	counts := make(map[string]map[string]int)
+	for _, result := range failures {
+		chain := result.GetChainName()
+		if counts[chain] == nil {
+			counts[chain] = make(map[string]int)
+		}
+		exchanges := routeExchanges(result.GetRoute())
+		if len(exchanges) == 0 {
+			exchanges = []string{"no route"}
+		}
+		for _, exchange := range exchanges {
+			counts[chain][exchange]++
+		}
+	}
+
+	chains := make([]string, 0, len(counts))
+	for chain := range counts {
+		chains = append(chains, chain)
+	}
+	sort.Strings(chains)
+
+	lines := make([]string, 0, len(chains))
+	for _, chain := range chains {
+		exchanges := make([]string, 0, len(counts[chain]))
+		for exchange := range counts[chain] {
+			exchanges = append(exchanges, exchange)
+		}
+		sort.Slice(exchanges, func(i, j int) bool {
+			if counts[chain][exchanges[i]] != counts[chain][exchanges[j]] {
+				return counts[chain][exchanges[i]] > counts[chain][exchanges[j]]
+			}
+			return exchanges[i] < exchanges[j]
+		})
+
+		parts := make([]string, 0, len(exchanges))
+		for _, exchange := range exchanges {
+			parts = append(parts, fmt.Sprintf("%s (%d)", exchange, counts[chain][exchange]))
+		}
+		lines = append(lines, fmt.Sprintf("*%s*: %s", chain, strings.Join(parts, ", ")))
+	}
+
+	return truncate(strings.Join(lines, "\n"), maxSectionTextLength-100)
+}
+
+// routeExchanges returns the distinct exchanges used by a route, in route order
+func routeExchanges(route [][]kyberswap.KyberSwapSwap) []string {
+	seen := make(map[string]bool)
+	var exchanges []string
+	for _, sequence := range route {
+		for _, swap := range sequence {
+			if swap.Exchange == "" || seen[swap.Exchange] {
+				continue
+			}
+			seen[swap.Exchange] = true
+			exchanges = append(exchanges, swap.Exchange)
+		}
+	}
+	return exchanges
+}
+
+// now returns the current time in the alert timezone
+func (c *Client) now() time.Time {
+	return time.Now().In(c.templates.location)
+}
+
+func newWebhookMessage(text string, blocks []goslack.Block) *goslack.WebhookMessage {
+	return &goslack.WebhookMessage{
+		Text:   text,
+		Blocks: &goslack.Blocks{BlockSet: blocks},
+	}
+}
+
+func headerBlock(text string) *goslack.HeaderBlock {
+	return goslack.NewHeaderBlock(goslack.NewTextBlockObject(goslack.PlainTextType, truncate(text, 150), true, false))
+}
+
+func sectionBlock(text string) *goslack.SectionBlock {
+	return goslack.NewSectionBlock(markdownText(text), nil, nil)
+}
+
+func markdownText(text string) *goslack.TextBlockObject {
+	return goslack.NewTextBlockObject(goslack.MarkdownType, truncate(text, maxSectionTextLength), false, false)
+}
+
+// blocksTextLength approximates the size of blocks by their text content
+func blocksTextLength(blocks []goslack.Block) int {
+	length := 0
+	for _, block := range blocks {
+		switch b := block.(type) {
+		case *goslack.SectionBlock:
+			if b.Text != nil {
+				length += len(b.Text.Text)
+			}
+			for _, field := range b.Fields {
+				length += len(field.Text)
+			}
+		case *goslack.HeaderBlock:
+			length += len(b.Text.Text)
+		}
+	}
+	return length
+}
+
+// escapeText escapes the characters Slack treats as control sequences in mrkdwn
+func escapeText(text string) string {
+	replacer := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	return replacer.Replace(text)
+}
+
+// truncate shortens text to at most limit bytes, marking the cut
+func truncate(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	cut := limit - len("…")
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "…"
+}
@@ -1,259 +1,155 @@
 package slack
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"net/http"
 	"scale-helper-monitor/internal/clients/kyberswap"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	goslack "github.com/slack-go/slack"
 )
 
 // MonitoringResult represents the result structure needed for alerts
 // This is a temporary interface until we fully refactor
 type MonitoringResult interface {
+	GetID() string
+	GetCase() string
 	GetChainName() string
 	GetTokenIn() string
 	GetTokenOut() string
 	GetAmount() string
+	GetSizeBucket() string
+	GetEnvironment() string
+	GetTxHash() string
+	GetScenario() string
 	GetNewAmount() string
+	GetBlockNumber() uint64
 	GetIsSuccess() bool
 	GetError() string
+	GetRevertReason() string
+	GetRevertContract() string
+	GetFailingFrame() string
+	GetTransfers() []string
 	GetInputData() string
 	GetReturnedData() string
 	GetRoute() [][]kyberswap.KyberSwapSwap
 	GetOriginalTenderlyURL() string
 	GetScaledTenderlyURL() string
+	GetOriginalSimulationPayload() string
+	GetScaledSimulationPayload() string
 }
 
 type Client struct {
 	webhookURL string
 	client     *http.Client
+	templates  *Templates
 	logger     *logrus.Logger
+
+	// Bot-token mode, alerts are threaded and carry file snippets
+	api     *goslack.Client
+	channel string
+	threads *ThreadStore
 }
 
-func NewClient(webhookURL string, timeout time.Duration, logger *logrus.Logger) *Client {
+// NewClient creates a webhook client. Nil templates use the defaults.
+func NewClient(webhookURL string, templates *Templates, timeout time.Duration, logger *logrus.Logger) *Client {
+	if templates == nil {
+		templates = DefaultTemplates()
+	}
+
 	return &Client{
 		webhookURL: webhookURL,
 		client: &http.Client{
 			Timeout: timeout,
 		},
-		logger: logger,
+		templates: templates,
+		logger:    logger,
 	}
 }
 
-// SendAlert sends an alert to Slack when monitoring fails
-func (c *Client) SendAlert(failures []MonitoringResult, totalTestCases int) error {
-	if c.webhookURL == "" {
-		c.logger.Warn("Slack webhook URL not configured, skipping alert")
-		return nil
+// NewBotClient creates a client that posts with a bot token. Each alert opens a
+// thread in the channel, or updates the open one, and stateFile keeps track of
+// the open thread between runs. Nil templates use the defaults.
+func NewBotClient(token, channel, stateFile string, templates *Templates, timeout time.Duration, logger *logrus.Logger) *Client {
+	if stateFile == "" {
+		stateFile = defaultThreadStateFile
 	}
-
-	// Create Slack message for results (both failures and success)
-	message := c.createAlertMessage(failures, totalTestCases)
-
-	// Marshal to JSON
-	payload, err := json.Marshal(message)
-	if err != nil {
-		return fmt.Errorf("failed to marshal Slack message: %w", err)
+	if templates == nil {
+		templates = DefaultTemplates()
 	}
 
-	// Send to Slack
-	resp, err := c.client.Post(c.webhookURL, "application/json", bytes.NewBuffer(payload))
-	if err != nil {
-		return fmt.Errorf("failed to send Slack message: %w", err)
+	httpClient := &http.Client{
+		Timeout: timeout,
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("Slack returned status %d", resp.StatusCode)
+	return &Client{
+		client:    httpClient,
+		templates: templates,
+		logger:    logger,
+		api:       goslack.New(token, goslack.OptionHTTPClient(httpClient)),
+		channel:   channel,
+		threads:   NewThreadStore(stateFile),
 	}
-
-	return nil
 }
 
-func (c *Client) createAlertMessage(failures []MonitoringResult, totalTestCases int) *Message {
-	failureCount := len(failures)
-	// Convert to GMT+7 timezone
-	loc, _ := time.LoadLocation("Asia/Bangkok") // GMT+7
-	localTime := time.Now().In(loc)
-
-	// Handle positive message when no failures
-	if failureCount == 0 {
-		title := fmt.Sprintf("✅ Scale Helper Monitor - All Tests Passed - %s",
-			localTime.Format(time.RFC1123))
-
-		summaryFields := []Field{
-			{
-				Title: "Status",
-				Value: "All systems operational",
-				Short: true,
-			},
-			{
-				Title: "Total Test Cases",
-				Value: fmt.Sprintf("%d", totalTestCases),
-				Short: true,
-			},
-			{
-				Title: "Success Rate",
-				Value: "100.00%",
-				Short: true,
-			},
-			{
-				Title: "Failures",
-				Value: "0",
-				Short: true,
-			},
-		}
-
-		summaryAttachment := Attachment{
-			Color:  "good",
-			Title:  "📊 Monitoring Summary",
-			Fields: summaryFields,
-		}
-
-		return &Message{
-			Text:        title,
-			Attachments: []Attachment{summaryAttachment},
-		}
+// SendAlert sends an alert to Slack when monitoring fails
+func (c *Client) SendAlert(failures []MonitoringResult, totalTestCases int) error {
+	if c.api != nil {
+		return c.sendThreadedAlert(context.Background(), failures, totalTestCases)
 	}
 
-	// Handle failure messages (existing logic)
-	title := fmt.Sprintf("🚨 Scale Helper Monitor Alert - %d Failures - %s",
-		failureCount, localTime.Format(time.RFC1123))
-
-	// Create a summary attachment
-	summaryFields := []Field{
-		{
-			Title: "Total Failures",
-			Value: fmt.Sprintf("%d", failureCount),
-			Short: true,
-		},
-		{
-			Title: "Total Test Cases",
-			Value: fmt.Sprintf("%d", totalTestCases),
-			Short: true,
-		},
-		{
-			Title: "Success Rate",
-			Value: fmt.Sprintf("%.2f%%", float64(totalTestCases-failureCount)/float64(totalTestCases)*100),
-			Short: true,
-		},
-		{
-			Title: "Affected Chains",
-			Value: c.formatChainList(c.getUniqueChains(failures)),
-			Short: true,
-		},
+	if c.webhookURL == "" {
+		c.logger.Warn("Slack webhook URL not configured, skipping alert")
+		return nil
 	}
 
-	summaryAttachment := Attachment{
-		Color:  "danger",
-		Title:  "📊 Alert Summary",
-		Fields: summaryFields,
-	}
+	// Render Block Kit messages for results (both failures and success),
+	// split into several messages when Slack limits would be exceeded
+	messages, firsts := c.renderAlertMessages(failures, totalTestCases)
 
-	attachments := []Attachment{summaryAttachment}
+	for i, message := range messages {
+		err := goslack.PostWebhookCustomHTTPContext(context.Background(), c.webhookURL, c.client, message)
+		if err == nil {
+			continue
+		}
 
-	// Create individual attachments for each failure
-	for i, result := range failures {
-		fields := c.createResultFields(result)
+		c.logger.WithError(err).WithFields(logrus.Fields{
+			"message": i + 1,
+			"total":   len(messages),
+		}).Warn("Failed to send Slack alert, falling back to truncated message")
 
-		attachment := Attachment{
-			Color:  "danger",
-			Title:  fmt.Sprintf("❌ Failure %d: %s", i+1, result.GetChainName()),
-			Fields: fields,
+		// Fall back to a truncated plain-text alert of the failures not sent yet
+		// rather than losing them
+		fallback := c.renderFallbackMessage(failures, totalTestCases, firsts[i])
+		if fallbackErr := goslack.PostWebhookCustomHTTPContext(context.Background(), c.webhookURL, c.client, fallback); fallbackErr != nil {
+			return fmt.Errorf("failed to send Slack message: %w (fallback: %v)", err, fallbackErr)
 		}
-		attachments = append(attachments, attachment)
+		return nil
 	}
 
-	return &Message{
-		Text:        title,
-		Attachments: attachments,
-	}
+	return nil
 }
 
-// createResultFields creates common fields for a monitoring result
-func (c *Client) createResultFields(result MonitoringResult) []Field {
-	fields := []Field{
-		{
-			Title: "Chain",
-			Value: result.GetChainName(),
-			Short: true,
-		},
-		{
-			Title: "Token Addresses",
-			Value: fmt.Sprintf("In: `%s`\nOut: `%s`", result.GetTokenIn(), result.GetTokenOut()),
-			Short: false,
-		},
-		{
-			Title: "Amount",
-			Value: result.GetAmount(),
-			Short: true,
-		},
-		{
-			Title: "New Amount",
-			Value: result.GetNewAmount(),
-			Short: true,
-		},
-	}
+// SendNotice posts a standalone message outside the alert thread, for findings
+// that are not tied to a failing test case
+func (c *Client) SendNotice(title string, lines []string) error {
+	text := truncate("• "+strings.Join(lines, "\n• "), maxSectionTextLength)
+	blocks := []goslack.Block{headerBlock(title), sectionBlock(text)}
 
-	// Add Tenderly simulation links
-	if originalURL := result.GetOriginalTenderlyURL(); originalURL != "" {
-		fields = append(fields, Field{
-			Title: "Original Swap Simulation",
-			Value: fmt.Sprintf("<%s|🔗 View in Tenderly>", originalURL),
-			Short: true,
-		})
+	if c.api != nil {
+		return c.postMessage(context.Background(), c.channel, goslack.MsgOptionText(title, false), goslack.MsgOptionBlocks(blocks...))
 	}
-
-	if scaledURL := result.GetScaledTenderlyURL(); scaledURL != "" {
-		fields = append(fields, Field{
-			Title: "Scaled Swap Simulation",
-			Value: fmt.Sprintf("<%s|🔗 View Failed Simulation>", scaledURL),
-			Short: true,
-		})
+	if c.webhookURL == "" {
+		c.logger.Warn("Slack webhook URL not configured, skipping notice")
+		return nil
 	}
-
-	// Add error field if there's an error
-	if result.GetError() != "" {
-		fields = append(fields, Field{
-			Title: "Error",
-			Value: fmt.Sprintf("```%s```", result.GetError()),
-			Short: false,
-		})
+	if err := goslack.PostWebhookCustomHTTPContext(context.Background(), c.webhookURL, c.client, newWebhookMessage(title, blocks)); err != nil {
+		return fmt.Errorf("failed to send Slack notice: %w", err)
 	}
-
-	// Add detailed route sequence information with pool types
-	route := result.GetRoute()
-	if len(route) > 0 {
-		routeInfo := fmt.Sprintf("```Sequence Steps: %d\n", len(route))
-
-		for i, sequence := range route {
-			routeInfo += fmt.Sprintf("\n--- Step %d ---\n", i+1)
-
-			if len(sequence) > 0 {
-				for j, swap := range sequence {
-					routeInfo += fmt.Sprintf("Pool %d:\n", j+1)
-					routeInfo += fmt.Sprintf("  Type: %s\n", swap.PoolType)
-					routeInfo += fmt.Sprintf("  Exchange: %s\n", swap.Exchange)
-					if j < len(sequence)-1 {
-						routeInfo += "\n"
-					}
-				}
-			}
-		}
-		routeInfo += "```"
-
-		fields = append(fields, Field{
-			Title: "Sequence Details",
-			Value: routeInfo,
-			Short: false,
-		})
-	}
-
-	return fields
+	return nil
 }
 
 // getUniqueChains extracts unique chain names from results
@@ -267,11 +163,48 @@ func (c *Client) getUniqueChains(results []MonitoringResult) []string {
 	for chain := range chainMap {
 		chains = append(chains, chain)
 	}
+	sort.Strings(chains)
 	return chains
 }
 
+// formatSizeBuckets lists failure counts per chain and size bucket, e.g. "bsc: 50k USDC (2)"
+func (c *Client) formatSizeBuckets(results []MonitoringResult) string {
+	counts := make(map[string]map[string]int)
+	var chains []string
+	for _, result := range results {
+		chain := result.GetChainName()
+		if _, exists := counts[chain]; !exists {
+			counts[chain] = make(map[string]int)
+			chains = append(chains, chain)
+		}
+		bucket := result.GetSizeBucket()
+		if bucket == "" {
+			bucket = "unknown size"
+		}
+		counts[chain][bucket]++
+	}
+	sort.Strings(chains)
+
+	lines := make([]string, 0, len(chains))
+	for _, chain := range chains {
+		buckets := make([]string, 0, len(counts[chain]))
+		for bucket := range counts[chain] {
+			buckets = append(buckets, bucket)
+		}
+		sort.Strings(buckets)
+
+		parts := make([]string, 0, len(buckets))
+		for _, bucket := range buckets {
+			parts = append(parts, fmt.Sprintf("%s (%d)", bucket, counts[chain][bucket]))
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", chain, strings.Join(parts, ", ")))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
 // formatChainList formats a list of chains for display
-func (c *Client) formatChainList(chains []string) string {
+func formatChainList(chains []string) string {
 	if len(chains) == 0 {
 		return "None"
 	}
@@ -0,0 +1,158 @@
+package slack
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TemplateConfig represents the alert templates and display timezone.
+// Empty templates fall back to the defaults below.
+type TemplateConfig struct {
+	Timezone string `mapstructure:"timezone"` // IANA name, defaults to Asia/Bangkok
+	Title    string `mapstructure:"title"`    // Plain-text header, rendered with SummaryData
+	Summary  string `mapstructure:"summary"`  // mrkdwn summary section, rendered with SummaryData
+	Failure  string `mapstructure:"failure"`  // mrkdwn section per failure, rendered with FailureData
+}
+
+// SummaryData is the model passed to the title and summary templates
+type SummaryData struct {
+	Time           time.Time // Alert time in the configured timezone
+	TotalTestCases int
+	Failures       int      // Number of failing test cases, 0 when everything passed
+	SuccessRate    float64  // Percentage of passing test cases
+	Chains         []string // Chains with failures
+	ChainDex       string   // Failures per chain and dex, one chain per line
+	SizeBuckets    string   // Failures per chain and size bucket, one chain per line
+}
+
+// FailureData is the model passed to the failure template
+type FailureData struct {
+	Index               int // 1-based position in the alert
+	ID                  string
+	ChainName           string
+	TokenIn             string
+	TokenOut            string
+	Amount              string // Original amount in base units
+	NewAmount           string // Scaled amount in base units
+	SizeBucket          string
+	Environment         string // Aggregator environment, empty unless the test case lists environments
+	TxHash              string // On-chain swap the calldata was sampled from, traffic replay only
+	Scenario            string // Edge scenario, e.g. "zero" or "ten_x", empty for the randomly scaled amount
+	BlockNumber         uint64
+	RouteSteps          int
+	Exchanges           []string // Distinct exchanges on the route, in route order
+	OriginalTenderlyURL string
+	ScaledTenderlyURL   string
+	Error               string   // Escaped for mrkdwn and truncated
+	RevertReason        string   // Decoded revert, e.g. "Return amount is not enough"
+	RevertContract      string   // Contract that reverted, when known
+	FailingFrame        string   // Deepest reverting call, e.g. "UniswapV3Pool.swap (0x88e6…)"
+	Transfers           []string // Asset changes of the failed simulation, capped at maxTransferLines
+}
+
+const defaultTimezone = "Asia/Bangkok" // GMT+7
+
+const defaultTitleTemplate = `{{if .Failures}}🚨 Scale Helper Monitor Alert - {{.Failures}} Failures{{else}}✅ Scale Helper Monitor - All Tests Passed{{end}} - {{.Time.Format "Mon, 02 Jan 2006 15:04:05 MST"}}`
+
+const defaultSummaryTemplate = `{{if .Failures -}}
+*Total Failures:* {{.Failures}} · *Total Test Cases:* {{.TotalTestCases}} · *Success Rate:* {{printf "%.2f" .SuccessRate}}%
+*Affected Chains:* {{chainList .Chains}}
+
+*📊 Failures by chain and dex*
+{{.ChainDex}}
+
+*📏 Failures by size*
+{{.SizeBuckets}}
+{{- else -}}
+*Status:* All systems operational
+*Total Test Cases:* {{.TotalTestCases}} · *Success Rate:* 100.00% · *Failures:* 0
+{{- end}}`
+
+const defaultFailureTemplate = `*❌ Failure {{.Index}}: {{.ChainName}}* ` + "`{{.TokenIn}}` → `{{.TokenOut}}`" + `
+{{if .Scenario}}edge {{.Scenario}} · {{end}}{{if .Environment}}env {{.Environment}} · {{end}}{{if .SizeBucket}}size {{.SizeBucket}} · {{end}}amount {{.Amount}} → {{.NewAmount}}{{if .BlockNumber}} · block {{.BlockNumber}}{{end}}{{if .ID}} · result ` + "`{{.ID}}`" + `{{end}}
+{{- if .TxHash}}
+Sampled from tx ` + "`{{.TxHash}}`" + `{{end}}
+{{- if .RouteSteps}}
+Route: {{.RouteSteps}} steps via {{join .Exchanges ", "}}{{end}}
+{{- if or .OriginalTenderlyURL .ScaledTenderlyURL}}
+{{if .OriginalTenderlyURL}}<{{.OriginalTenderlyURL}}|🔗 Original simulation>{{end}}{{if and .OriginalTenderlyURL .ScaledTenderlyURL}} · {{end}}{{if .ScaledTenderlyURL}}<{{.ScaledTenderlyURL}}|🔗 Failed scaled simulation>{{end}}{{end}}
+{{- if .FailingFrame}}
+Reverted in: ` + "`{{.FailingFrame}}`" + `{{end}}
+{{- if .Transfers}}
+Transfers before the revert:{{range .Transfers}}
+• {{.}}{{end}}{{end}}
+{{- if .RevertReason}}
+Reason: ` + "`{{.RevertReason}}`" + `{{if .RevertContract}} in ` + "`{{.RevertContract}}`" + `{{end}}{{end}}
+{{- if .Error}}
+` + "```{{.Error}}```" + `{{end}}`
+
+// Templates holds the parsed alert templates and display location
+type Templates struct {
+	location *time.Location
+	title    *template.Template
+	summary  *template.Template
+	failure  *template.Template
+}
+
+// NewTemplates parses the configured templates and loads the display timezone
+func NewTemplates(cfg TemplateConfig) (*Templates, error) {
+	timezone := cfg.Timezone
+	if timezone == "" {
+		timezone = defaultTimezone
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid alert timezone %q: %w", timezone, err)
+	}
+
+	t := &Templates{location: location}
+	if t.title, err = parseTemplate("title", cfg.Title, defaultTitleTemplate); err != nil {
+		return nil, err
+	}
+	if t.summary, err = parseTemplate("summary", cfg.Summary, defaultSummaryTemplate); err != nil {
+		return nil, err
+	}
+	if t.failure, err = parseTemplate("failure", cfg.Failure, defaultFailureTemplate); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// DefaultTemplates returns the built-in templates in the default timezone
+func DefaultTemplates() *Templates {
+	t, err := NewTemplates(TemplateConfig{})
+	if err != nil {
+		// Only reachable without tzdata, keep alerting in UTC
+		t, _ = NewTemplates(TemplateConfig{Timezone: "UTC"})
+	}
+	return t
+}
+
+func parseTemplate(name, text, fallback string) (*template.Template, error) {
+	if strings.TrimSpace(text) == "" {
+		text = fallback
+	}
+
+	tmpl, err := template.New(name).Funcs(template.FuncMap{
+		"join":      strings.Join,
+		"chainList": formatChainList,
+	}).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s alert template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// execute renders a template, falling back to the error text so a broken
+// template never drops the alert
+func execute(tmpl *template.Template, data interface{}) string {
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return fmt.Sprintf("failed to render %s template: %v", tmpl.Name(), err)
+	}
+	return b.String()
+}
@@ -0,0 +1,304 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	goslack "github.com/slack-go/slack"
+)
+
+// ThreadState represents the open alert thread in bot-token mode
+type ThreadState struct {
+	ChannelID   string   `json:"channel_id"`
+	ThreadTS    string   `json:"thread_ts"`
+	Failures    []string `json:"failures"` // Keys of the failures already posted in the thread
+	OpenedAt    string   `json:"opened_at"`
+	LastUpdated string   `json:"last_updated"`
+}
+
+// ThreadStore handles persistence of the open alert thread
+type ThreadStore struct {
+	stateFile string
+}
+
+// NewThreadStore creates a new thread store
+func NewThreadStore(stateFile string) *ThreadStore {
+	return &ThreadStore{
+		stateFile: stateFile,
+	}
+}
+
+// Load returns the open thread, or nil when there is none
+func (ts *ThreadStore) Load() (*ThreadState, error) {
+	data, err := os.ReadFile(ts.stateFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read thread state file: %v", err)
+	}
+
+	var state ThreadState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse thread state file: %v", err)
+	}
+
+	return &state, nil
+}
+
+// Save saves the open thread
+func (ts *ThreadStore) Save(state *ThreadState) error {
+	if err := os.MkdirAll(filepath.Dir(ts.stateFile), 0755); err != nil {
+		return fmt.Errorf("failed to create thread state directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal thread state: %v", err)
+	}
+
+	if err := os.WriteFile(ts.stateFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write thread state file: %v", err)
+	}
+
+	return nil
+}
+
+// Clear removes the open thread once it is resolved
+func (ts *ThreadStore) Clear() error {
+	if err := os.Remove(ts.stateFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear thread state file: %v", err)
+	}
+	return nil
+}
+
+// sendThreadedAlert posts the run summary as the parent message and each new
+// failure as a threaded reply with its calldata and Tenderly payloads attached.
+// While failures persist the open thread is reused; a run without failures
+// marks it as resolved.
+func (c *Client) sendThreadedAlert(ctx context.Context, failures []MonitoringResult, totalTestCases int) error {
+	state, err := c.threads.Load()
+	if err != nil {
+		c.logger.WithError(err).Warn("Failed to load Slack thread state, opening a new thread")
+		state = nil
+	}
+
+	title, summary := c.renderSummary(failures, totalTestCases)
+
+	if len(failures) == 0 {
+		if state == nil {
+			return c.postMessage(ctx, c.channel, goslack.MsgOptionText(title, false), goslack.MsgOptionBlocks(summary...))
+		}
+		return c.resolveThread(ctx, state, title, summary)
+	}
+
+	now := c.now().Format(time.RFC1123)
+	summary = append(summary, goslack.NewContextBlock("",
+		markdownText("Failure details, calldata and Tenderly payloads are in the thread")))
+
+	if state == nil {
+		channelID, threadTS, err := c.api.PostMessageContext(ctx, c.channel,
+			goslack.MsgOptionText(title, false), goslack.MsgOptionBlocks(summary...))
+		if err != nil {
+			return fmt.Errorf("failed to post Slack alert: %w", err)
+		}
+		state = &ThreadState{ChannelID: channelID, ThreadTS: threadTS, OpenedAt: now}
+	} else {
+		// Keep the parent message in line with the latest run
+		if _, _, _, err := c.api.UpdateMessageContext(ctx, state.ChannelID, state.ThreadTS,
+			goslack.MsgOptionText(title, false), goslack.MsgOptionBlocks(summary...)); err != nil {
+			c.logger.WithError(err).Warn("Failed to update Slack alert thread")
+		}
+	}
+
+	posted := make(map[string]bool, len(state.Failures))
+	for _, key := range state.Failures {
+		posted[key] = true
+	}
+
+	// Only failures posted to the thread are recorded, the others are retried next run
+	current := make([]string, 0, len(failures))
+	isCurrent := make(map[string]bool, len(failures))
+	for i, result := range failures {
+		key := failureKey(result)
+		isCurrent[key] = true
+		if posted[key] {
+			current = append(current, key)
+			continue
+		}
+
+		err := c.postMessage(ctx, state.ChannelID,
+			goslack.MsgOptionTS(state.ThreadTS),
+			goslack.MsgOptionText(fmt.Sprintf("Failure %d: %s", i+1, key), false),
+			goslack.MsgOptionBlocks(sectionBlock(c.formatFailureSection(i+1, result))))
+		if err != nil {
+			c.logger.WithError(err).WithField("failure", key).Warn("Failed to post failure to Slack thread")
+			continue
+		}
+		current = append(current, key)
+		c.uploadSnippets(ctx, state, result)
+	}
+
+	// Note the failures that no longer reproduce
+	var resolved []string
+	for _, key := range state.Failures {
+		if !isCurrent[key] {
+			resolved = append(resolved, key)
+		}
+	}
+	if len(resolved) > 0 {
+		text := fmt.Sprintf("✅ Resolved since the previous run:\n• %s", strings.Join(resolved, "\n• "))
+		if err := c.postMessage(ctx, state.ChannelID, goslack.MsgOptionTS(state.ThreadTS),
+			goslack.MsgOptionText(truncate(text, maxSectionTextLength), false)); err != nil {
+			c.logger.WithError(err).Warn("Failed to post resolved failures to Slack thread")
+		}
+	}
+
+	state.Failures = current
+	state.LastUpdated = now
+	return c.threads.Save(state)
+}
+
+// ResolveAlert marks the open alert thread as resolved after a run without
+// failures. It does nothing in webhook mode or when no thread is open.
+func (c *Client) ResolveAlert(totalTestCases int) error {
+	if c.api == nil {
+		return nil
+	}
+
+	state, err := c.threads.Load()
+	if err != nil || state == nil {
+		return err
+	}
+
+	title, summary := c.renderSummary(nil, totalTestCases)
+	return c.resolveThread(context.Background(), state, title, summary)
+}
+
+// resolveThread marks the open thread as resolved after a run without failures
+func (c *Client) resolveThread(ctx context.Context, state *ThreadState, title string, summary []goslack.Block) error {
+	now := c.now().Format(time.RFC1123)
+	resolvedTitle := fmt.Sprintf("✅ Resolved - Scale Helper Monitor Alert opened %s", state.OpenedAt)
+	blocks := append([]goslack.Block{headerBlock(resolvedTitle)}, summary[1:]...)
+	blocks = append(blocks, goslack.NewContextBlock("", markdownText(fmt.Sprintf("Resolved at %s", now))))
+
+	if _, _, _, err := c.api.UpdateMessageContext(ctx, state.ChannelID, state.ThreadTS,
+		goslack.MsgOptionText(resolvedTitle, false), goslack.MsgOptionBlocks(blocks...)); err != nil {
+		return fmt.Errorf("failed to update Slack alert thread: %w", err)
+	}
+
+	if err := c.postMessage(ctx, state.ChannelID, goslack.MsgOptionTS(state.ThreadTS),
+		goslack.MsgOptionText(title, false)); err != nil {
+		c.logger.WithError(err).Warn("Failed to post resolution to Slack thread")
+	}
+
+	return c.threads.Clear()
+}
+
+// uploadSnippets attaches the full calldata and Tenderly payloads of a failure to the thread
+func (c *Client) uploadSnippets(ctx context.Context, state *ThreadState, result MonitoringResult) {
+	name := result.GetID()
+	if name == "" {
+		name = strings.ReplaceAll(failureKey(result), "/", "-")
+	}
+
+	var calldata strings.Builder
+	fmt.Fprintf(&calldata, "Original calldata:\n%s\n\n", result.GetInputData())
+	fmt.Fprintf(&calldata, "Scaled calldata (new amount %s):\n%s\n", result.GetNewAmount(), result.GetReturnedData())
+
+	snippets := []goslack.UploadFileV2Parameters{{
+		Filename:    name + "-calldata.txt",
+		Title:       "Calldata " + name,
+		Content:     calldata.String(),
+		SnippetType: "text",
+	}}
+
+	payloads := make(map[string]json.RawMessage)
+	if payload := result.GetOriginalSimulationPayload(); payload != "" {
+		payloads["original"] = json.RawMessage(payload)
+	}
+	if payload := result.GetScaledSimulationPayload(); payload != "" {
+		payloads["scaled"] = json.RawMessage(payload)
+	}
+	if len(payloads) > 0 {
+		data, err := json.MarshalIndent(payloads, "", "  ")
+		if err == nil {
+			snippets = append(snippets, goslack.UploadFileV2Parameters{
+				Filename:    name + "-tenderly.json",
+				Title:       "Tenderly payloads " + name,
+				Content:     string(data),
+				SnippetType: "json",
+			})
+		}
+	}
+
+	for _, snippet := range snippets {
+		snippet.Channel = state.ChannelID
+		snippet.ThreadTimestamp = state.ThreadTS
+		snippet.FileSize = len(snippet.Content)
+
+		err := c.withRateLimit(ctx, func() error {
+			_, err := c.api.UploadFileV2Context(ctx, snippet)
+			return err
+		})
+		if err != nil {
+			c.logger.WithError(err).WithFields(logrus.Fields{
+				"file":   snippet.Filename,
+				"result": result.GetID(),
+			}).Warn("Failed to upload snippet to Slack thread")
+		}
+	}
+}
+
+// postMessage posts a message, waiting once if Slack rate limits the call
+func (c *Client) postMessage(ctx context.Context, channel string, options ...goslack.MsgOption) error {
+	return c.withRateLimit(ctx, func() error {
+		_, _, err := c.api.PostMessageContext(ctx, channel, options...)
+		return err
+	})
+}
+
+// withRateLimit runs call and retries it once after the delay Slack asks for
+func (c *Client) withRateLimit(ctx context.Context, call func() error) error {
+	err := call()
+
+	var rateLimited *goslack.RateLimitedError
+	if !errors.As(err, &rateLimited) {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(rateLimited.RetryAfter):
+	}
+	return call()
+}
+
+// failureKey identifies a failing test case across runs
+func failureKey(result MonitoringResult) string {
+	key := fmt.Sprintf("%s/%s-%s", result.GetChainName(), result.GetTokenIn(), result.GetTokenOut())
+	if testCase := result.GetCase(); testCase != "" {
+		key = fmt.Sprintf("%s %s", testCase, key)
+	}
+	if sizeBucket := result.GetSizeBucket(); sizeBucket != "" {
+		key = fmt.Sprintf("%s (%s)", key, sizeBucket)
+	}
+	if environment := result.GetEnvironment(); environment != "" {
+		key = fmt.Sprintf("%s [%s]", key, environment)
+	}
+	if txHash := result.GetTxHash(); txHash != "" {
+		key = fmt.Sprintf("%s tx %s", key, txHash)
+	}
+	if scenario := result.GetScenario(); scenario != "" {
+		key = fmt.Sprintf("%s edge %s", key, scenario)
+	}
+	return key
+}
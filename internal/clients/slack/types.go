@@ -1,21 +1,14 @@
 package slack
 
-// Message represents a Slack message
-type Message struct {
-	Text        string       `json:"text"`
-	Attachments []Attachment `json:"attachments,omitempty"`
-}
+// Slack Block Kit limits, see https://api.slack.com/reference/block-kit/blocks
+const (
+	maxBlocksPerMessage  = 50
+	maxSectionTextLength = 3000
+	maxMessageLength     = 12000 // Total text per message, kept well below Slack's hard limit
+	maxErrorLength       = 500
+	maxFallbackFailures  = 20
+	maxTransferLines     = 5
+)
 
-// Attachment represents a Slack message attachment
-type Attachment struct {
-	Color  string  `json:"color"`
-	Title  string  `json:"title"`
-	Fields []Field `json:"fields"`
-}
-
-// Field represents a Slack message field
-type Field struct {
-	Title string `json:"title"`
-	Value string `json:"value"`
-	Short bool   `json:"short"`
-}
+// defaultThreadStateFile keeps the open alert thread in bot-token mode
+const defaultThreadStateFile = "data/slack-thread.json"
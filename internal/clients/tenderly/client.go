@@ -5,9 +5,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"scale-helper-monitor/internal/storage"
 )
 
 // Constants for special addresses
@@ -16,21 +22,35 @@ const (
 	NATIVE_ADDRESS = "0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE"
 )
 
+// DefaultAPIBaseURL is the public Tenderly API
+const DefaultAPIBaseURL = "https://api.tenderly.co/api/v1"
+
 // NewClient creates a new Tenderly client
-func NewClient(accessKey, username, project string, timeout time.Duration) *Client {
+func NewClient(accessKey, username, project string, options Options, timeout time.Duration) *Client {
+	baseURL := options.APIBaseURL
+	if baseURL == "" {
+		baseURL = DefaultAPIBaseURL
+	}
 	return &Client{
-		accessKey: accessKey,
-		username:  username,
-		project:   project,
-		baseURL:   "https://api.tenderly.co/api/v1",
+		accessKey:        accessKey,
+		username:         username,
+		project:          project,
+		baseURL:          baseURL,
+		saveOnlyFailures: options.SaveOnlyFailures,
+		usage:            newUsageTracker(options.UsageFile, options.DailyLimit),
 		client: &http.Client{
 			Timeout: timeout,
 		},
 	}
 }
 
-// CreateStateObjectsForSwap creates state objects for token balances and approvals
-func (c *Client) CreateStateObjectsForSwap(tokenIn, routerAddress, fromAddress, amount string, chainName string, balanceSlot string) (map[string]interface{}, error) {
+// maxAllowance is written to the allowance slot so the router can always pull the input
+const maxAllowance = "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"
+
+// CreateStateObjectsForSwap creates state objects for token balances and approvals,
+// computing the balance slot of fromAddress and its allowance slot for routerAddress.
+// Without an allowance slot the allowance is left to an approve in the bundle.
+func (c *Client) CreateStateObjectsForSwap(tokenIn, routerAddress, fromAddress, amount string, chainName string, layout storage.TokenLayout) (map[string]interface{}, error) {
 	stateObjects := make(map[string]interface{})
 
 	stateObjects[fromAddress] = map[string]interface{}{
@@ -38,24 +58,53 @@ func (c *Client) CreateStateObjectsForSwap(tokenIn, routerAddress, fromAddress,
 	}
 
 	// Skip token state manipulation for native token
-	if strings.EqualFold(tokenIn, NATIVE_ADDRESS) {
+	if IsNative(tokenIn) {
 		return stateObjects, nil
 	}
 
-	// Set token balance following sim.py pattern
+	balanceSlot, err := layout.BalanceOf(fromAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute balance slot of %s: %w", tokenIn, err)
+	}
+	allowanceSlot, err := layout.AllowanceOf(fromAddress, routerAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute allowance slot of %s: %w", tokenIn, err)
+	}
+
+	// Set token balance following sim.py pattern, and the router allowance next to it
+	slots := map[string]string{
+		balanceSlot: "0x7fffffffffffffff0123456789abcdef",
+	}
+	if allowanceSlot != "" {
+		slots[allowanceSlot] = maxAllowance
+	}
 	stateObjects[tokenIn] = map[string]interface{}{
-		"storage": map[string]string{
-			balanceSlot: "0x7fffffffffffffff0123456789abcdef",
-		},
+		"storage": slots,
 	}
 
 	return stateObjects, nil
 }
 
+// NeedsApproval tells whether a swap bundle must approve the router before
+// swapping, i.e. the input is an ERC20 whose allowance slot is unknown
+func NeedsApproval(tokenIn string, layout storage.TokenLayout) bool {
+	return !IsNative(tokenIn) && layout.AllowanceSlot == ""
+}
+
+// IsNative tells whether a token address is the native token placeholder
+func IsNative(token string) bool {
+	return strings.EqualFold(token, NATIVE_ADDRESS)
+}
+
 // SimulateTransactionBundle simulates a transaction using Tenderly's simulate-bundle endpoint
 func (c *Client) SimulateTransactionBundle(ctx context.Context, bundleReq *SimulationBundleRequest) (*SimulationBundleResponse, error) {
 	url := fmt.Sprintf("%s/account/%s/project/%s/simulate-bundle", c.baseURL, c.username, c.project)
 
+	// Every simulation of the bundle counts against the quota
+	if err := c.usage.reserve(len(bundleReq.Simulations)); err != nil {
+		return nil, err
+	}
+
 	jsonData, err := json.Marshal(bundleReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal simulation bundle request: %w", err)
@@ -76,7 +125,11 @@ func (c *Client) SimulateTransactionBundle(ctx context.Context, bundleReq *Simul
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("simulation failed with status %d %s", resp.StatusCode, resp.Body)
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if isQuotaResponse(resp.StatusCode, body) {
+			return nil, &QuotaError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+		}
+		return nil, fmt.Errorf("simulation failed with status %d %s", resp.StatusCode, body)
 	}
 
 	var bundleResp SimulationBundleResponse
@@ -87,46 +140,124 @@ func (c *Client) SimulateTransactionBundle(ctx context.Context, bundleReq *Simul
 	return &bundleResp, nil
 }
 
-// SimulateTransaction provides a simpler interface that matches our existing code
-func (c *Client) SimulateTransaction(ctx context.Context, networkID, tokenIn, from, to, input, value string, stateObjects map[string]interface{}) (bool, string, string, error) {
-	approvalReq := c.CreateApprovalData(networkID, from, to, tokenIn)
-	swapReq := &SimulationRequest{
+// SimulateTransaction provides a simpler interface that matches our existing code.
+// A zero blockNumber simulates against the latest block.
+func (c *Client) SimulateTransaction(ctx context.Context, networkID, tokenIn, from, to, input, value string, stateObjects map[string]interface{}, approve bool, blockNumber uint64) (*SwapSimulation, error) {
+	return c.SimulateSwapBundle(ctx, c.NewSwapBundle(networkID, tokenIn, from, to, input, value, stateObjects, approve, blockNumber))
+}
+
+// NewSwapBundle builds the bundle simulated for a swap. With approve set the
+// swap is preceded by an approve of the router, see NeedsApproval.
+// A zero blockNumber simulates against the latest block.
+func (c *Client) NewSwapBundle(networkID, tokenIn, from, to, input, value string, stateObjects map[string]interface{}, approve bool, blockNumber uint64) *SimulationBundleRequest {
+	bundle := &SimulationBundleRequest{}
+	if approve {
+		approvalReq := c.CreateApprovalData(networkID, from, to, tokenIn)
+		approvalReq.BlockNumber = blockNumber
+		bundle.Simulations = append(bundle.Simulations, *approvalReq)
+	}
+
+	swapReq := SimulationRequest{
+		BlockNumber:    blockNumber,
 		NetworkID:      networkID,
 		From:           from,
 		To:             to,
 		GasLimit:       9999999999999,
 		Value:          value,
 		Input:          input,
-		Save:           true,
+		Save:           !c.saveOnlyFailures,
 		SaveIfFails:    true,
 		SimulationType: "quick",
 		StateObjects:   stateObjects,
 	}
 
-	bundleResp, err := c.SimulateTransactionBundle(ctx, &SimulationBundleRequest{
-		Simulations: []SimulationRequest{*approvalReq, *swapReq},
-	})
+	bundle.Simulations = append(bundle.Simulations, swapReq)
+
+	return bundle
+}
+
+// SimulateSwapBundle simulates a bundle built by NewSwapBundle and returns the
+// outcome of the swap. Failed swaps are simulated again with the full
+// simulation type to capture the call trace and asset changes.
+func (c *Client) SimulateSwapBundle(ctx context.Context, bundleReq *SimulationBundleRequest) (*SwapSimulation, error) {
+	simulation, err := c.simulateSwap(ctx, bundleReq)
+	if err != nil || simulation.Success || simulation.Failure != nil {
+		return simulation, err
+	}
+
+	// Quick simulations carry no trace, rerun the bundle in full mode
+	fullReq := &SimulationBundleRequest{Simulations: make([]SimulationRequest, len(bundleReq.Simulations))}
+	for i, req := range bundleReq.Simulations {
+		req.SimulationType = "full"
+		fullReq.Simulations[i] = req
+	}
+
+	full, err := c.simulateSwap(ctx, fullReq)
+	if err != nil || full.Success {
+		// Keep the quick result, a trace is a nice-to-have
+		return simulation, nil
+	}
+	return full, nil
+}
+
+// simulateSwap runs a bundle and extracts the outcome of the swap
+func (c *Client) simulateSwap(ctx context.Context, bundleReq *SimulationBundleRequest) (*SwapSimulation, error) {
+	bundleResp, err := c.SimulateTransactionBundle(ctx, bundleReq)
 	if err != nil {
-		return false, "", "", err
+		return nil, err
 	}
 
-	if len(bundleResp.SimulationResults) == 0 {
-		return false, "", "", fmt.Errorf("no simulation results returned")
+	if len(bundleResp.SimulationResults) != len(bundleReq.Simulations) {
+		return nil, fmt.Errorf("expected %d simulation results, got %d", len(bundleReq.Simulations), len(bundleResp.SimulationResults))
 	}
 
-	result := bundleResp.SimulationResults[1]
+	// The swap is always the last simulation of the bundle
+	result := bundleResp.SimulationResults[len(bundleResp.SimulationResults)-1]
+
+	simulation := &SwapSimulation{
+		ErrorMessage: result.Simulation.ErrorMessage,
+	}
+	// Generate Tenderly URL, unsaved simulations have none
+	if result.Simulation.ID != "" {
+		simulation.URL = fmt.Sprintf("https://dashboard.tenderly.co/%s/%s/simulator/%s",
+			c.username, c.project, result.Simulation.ID)
+	}
 
-	// Generate Tenderly URL
-	tenderlyURL := fmt.Sprintf("https://dashboard.tenderly.co/%s/%s/simulator/%s",
-		c.username, c.project, result.Simulation.ID)
+	// If transaction is nil, it failed
+	if result.Transaction == nil {
+		return simulation, nil
+	}
 
 	// Check if transaction was successful
-	if result.Transaction != nil {
-		return result.Transaction.Status, result.Simulation.ErrorMessage, tenderlyURL, nil
+	simulation.Success = result.Transaction.Status
+	if result.Transaction.GasUsed > 0 {
+		simulation.GasUsed = uint64(result.Transaction.GasUsed)
+	}
+	if simulation.Success {
+		return simulation, nil
 	}
 
-	// If transaction is nil, it failed
-	return false, result.Simulation.ErrorMessage, tenderlyURL, nil
+	if result.Transaction.ErrorInfo != nil {
+		simulation.ErrorAddress = result.Transaction.ErrorInfo.Address
+	}
+	if info := result.Transaction.TransactionInfo; info != nil && info.CallTrace != nil {
+		simulation.RevertData = info.CallTrace.Output
+		simulation.Failure = SummarizeFailure(info)
+	}
+
+	return simulation, nil
+}
+
+// isQuotaResponse tells whether Tenderly rejected a request for billing or rate limit reasons
+func isQuotaResponse(statusCode int, body []byte) bool {
+	switch statusCode {
+	case http.StatusPaymentRequired, http.StatusTooManyRequests:
+		return true
+	case http.StatusForbidden:
+		lower := strings.ToLower(string(body))
+		return strings.Contains(lower, "quota") || strings.Contains(lower, "limit")
+	}
+	return false
 }
 
 // GetChainNetworkID converts chain ID to Tenderly network ID
@@ -151,18 +282,13 @@ func GetChainNetworkID(chainID int) string {
 
 // CreateApprovalData creates approval transaction data for token approvals
 func (c *Client) CreateApprovalData(networkID, sender, routerAddress, tokenToApprove string) *SimulationRequest {
-	amount := "ffffffffffffffffffffffffffffffff"
-
-	// Remove 0x prefix from router address
-	routerWithoutPrefix := strings.TrimPrefix(routerAddress, "0x")
-
-	// ERC20 approve function: approve(address spender, uint256 amount)
-	// Function selector: 0x095ea7b3
-	input := fmt.Sprintf("0x095ea7b3000000000000000000000000%s00000000000000000000000000000000%s",
-		routerWithoutPrefix, amount)
+	// ERC20 approve(address spender, uint256 amount), selector 0x095ea7b3
+	spender := common.LeftPadBytes(common.HexToAddress(routerAddress).Bytes(), 32)
+	amount := common.FromHex(maxAllowance)
+	input := hexutil.Encode(append(append(common.FromHex("0x095ea7b3"), spender...), amount...))
 
 	return &SimulationRequest{
-		Save:           true,
+		Save:           !c.saveOnlyFailures,
 		SaveIfFails:    true,
 		SimulationType: "quick",
 		NetworkID:      networkID,
@@ -0,0 +1,88 @@
+package tenderly
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxTransfers caps the asset changes kept in a failure summary
+const maxTransfers = 20
+
+// SummarizeFailure finds the deepest reverting frame of a trace and collects
+// the asset changes of the simulation
+func SummarizeFailure(info *TransactionInfo) *FailureSummary {
+	if info == nil || info.CallTrace == nil {
+		return nil
+	}
+
+	frame, depth := deepestRevert(info.CallTrace, 0)
+	if frame == nil {
+		return nil
+	}
+
+	errorMessage := frame.ErrorReason
+	if errorMessage == "" {
+		errorMessage = frame.Error
+	}
+	summary := &FailureSummary{
+		Contract:     frame.To,
+		ContractName: frame.ContractName,
+		Function:     frame.FunctionName,
+		Error:        errorMessage,
+		Output:       frame.Output,
+		Depth:        depth,
+	}
+
+	for _, change := range info.AssetChanges {
+		if len(summary.Transfers) == maxTransfers {
+			break
+		}
+		summary.Transfers = append(summary.Transfers, TokenTransfer{
+			Type:   change.Type,
+			Token:  change.TokenInfo.ContractAddress,
+			Symbol: change.TokenInfo.Symbol,
+			From:   change.From,
+			To:     change.To,
+			Amount: change.Amount,
+		})
+	}
+
+	return summary
+}
+
+// deepestRevert returns the deepest frame with an error. Reverts bubble up
+// through every caller, so the deepest one is where the failure started.
+func deepestRevert(frame *CallTrace, depth int) (*CallTrace, int) {
+	if frame.Error == "" {
+		return nil, 0
+	}
+
+	deepest, deepestDepth := frame, depth
+	for _, call := range frame.Calls {
+		if found, foundDepth := deepestRevert(call, depth+1); found != nil && foundDepth >= deepestDepth {
+			deepest, deepestDepth = found, foundDepth
+		}
+	}
+	return deepest, deepestDepth
+}
+
+// Location names the reverting frame, e.g. "UniswapV3Pool.swap (0x88e6…)"
+func (s *FailureSummary) Location() string {
+	name := s.ContractName
+	if name == "" {
+		name = "unknown contract"
+	}
+	if s.Function != "" {
+		name = fmt.Sprintf("%s.%s", name, s.Function)
+	}
+	return fmt.Sprintf("%s (%s)", name, s.Contract)
+}
+
+// String formats a transfer, e.g. "Transfer 1.5 USDC 0xabc → 0xdef"
+func (t TokenTransfer) String() string {
+	token := t.Symbol
+	if token == "" {
+		token = t.Token
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s %s %s %s → %s", t.Type, t.Amount, token, t.From, t.To))
+}
@@ -4,11 +4,21 @@ import "net/http"
 
 // Client represents a Tenderly API client
 type Client struct {
-	accessKey string
-	username  string
-	project   string
-	baseURL   string
-	client    *http.Client
+	accessKey        string
+	username         string
+	project          string
+	baseURL          string
+	saveOnlyFailures bool
+	usage            *usageTracker
+	client           *http.Client
+}
+
+// Options represents the optional Tenderly client settings
+type Options struct {
+	SaveOnlyFailures bool   // Keep only failing simulations in the Tenderly project
+	DailyLimit       int    // Simulations per UTC day before failing with QuotaError, 0 disables
+	UsageFile        string // Persists the daily count across restarts
+	APIBaseURL       string // Defaults to DefaultAPIBaseURL
 }
 
 // SimulationRequest represents a Tenderly simulation request
@@ -20,6 +30,7 @@ type SimulationRequest struct {
 	Value          string                 `json:"value,omitempty"`
 	GasLimit       int64                  `json:"gas,omitempty"`
 	GasPrice       string                 `json:"gas_price,omitempty"`
+	BlockNumber    uint64                 `json:"block_number,omitempty"`
 	Save           bool                   `json:"save"`
 	SaveIfFails    bool                   `json:"save_if_fails"`
 	SimulationType string                 `json:"simulation_type,omitempty"`
@@ -33,30 +44,96 @@ type SimulationBundleRequest struct {
 
 // SimulationBundleResponse represents the bundle response structure
 type SimulationBundleResponse struct {
-	SimulationResults []struct {
-		Transaction *struct {
-			Hash    string `json:"hash"`
-			GasUsed int64  `json:"gas_used"`
-			Status  bool   `json:"status"`
-		} `json:"transaction"`
-		Simulation struct {
-			ID           string `json:"id"`
-			Status       bool   `json:"status"`
-			ErrorMessage string `json:"error_message,omitempty"`
-		} `json:"simulation"`
-	} `json:"simulation_results"`
-}
-
-// SimulationResponse represents a Tenderly simulation response
-type SimulationResponse struct {
-	Simulation struct {
-		ID        string `json:"id"`
-		Status    bool   `json:"status"`
-		ErrorInfo *struct {
-			ErrorMessage string `json:"error_message"`
-			Address      string `json:"address"`
-		} `json:"error_info,omitempty"`
-		GasUsed int64  `json:"gas_used"`
-		URL     string `json:"url,omitempty"`
+	SimulationResults []SimulationResult `json:"simulation_results"`
+}
+
+// SimulationResult represents one simulation of a bundle
+type SimulationResult struct {
+	Transaction *SimulatedTransaction `json:"transaction"`
+	Simulation  struct {
+		ID           string `json:"id"`
+		Status       bool   `json:"status"`
+		ErrorMessage string `json:"error_message,omitempty"`
 	} `json:"simulation"`
 }
+
+// SimulatedTransaction represents the transaction of a simulation
+type SimulatedTransaction struct {
+	Hash         string `json:"hash"`
+	GasUsed      int64  `json:"gas_used"`
+	Status       bool   `json:"status"`
+	ErrorMessage string `json:"error_message,omitempty"`
+	ErrorInfo    *struct {
+		ErrorMessage string `json:"error_message"`
+		Address      string `json:"address"`
+	} `json:"error_info,omitempty"`
+	TransactionInfo *TransactionInfo `json:"transaction_info,omitempty"`
+}
+
+// TransactionInfo holds the trace details, populated for full simulations
+type TransactionInfo struct {
+	CallTrace    *CallTrace    `json:"call_trace,omitempty"`
+	AssetChanges []AssetChange `json:"asset_changes,omitempty"`
+}
+
+// CallTrace represents one call frame and its sub-calls
+type CallTrace struct {
+	CallType     string       `json:"call_type"`
+	From         string       `json:"from"`
+	To           string       `json:"to"`
+	ContractName string       `json:"contract_name,omitempty"`
+	FunctionName string       `json:"function_name,omitempty"`
+	Input        string       `json:"input,omitempty"`
+	Output       string       `json:"output,omitempty"`
+	Error        string       `json:"error,omitempty"`
+	ErrorReason  string       `json:"error_reason,omitempty"`
+	Calls        []*CallTrace `json:"calls,omitempty"`
+}
+
+// AssetChange represents a token or native transfer, mint or burn
+type AssetChange struct {
+	TokenInfo struct {
+		Standard        string `json:"standard"`
+		ContractAddress string `json:"contract_address"`
+		Symbol          string `json:"symbol"`
+		Decimals        int    `json:"decimals"`
+	} `json:"token_info"`
+	Type      string `json:"type"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Amount    string `json:"amount"`
+	RawAmount string `json:"raw_amount"`
+}
+
+// FailureSummary condenses the trace of a failed simulation to the frame that
+// raised the error and the tokens that moved before it
+type FailureSummary struct {
+	Contract     string          `json:"contract"`
+	ContractName string          `json:"contract_name,omitempty"`
+	Function     string          `json:"function,omitempty"`
+	Error        string          `json:"error,omitempty"`
+	Output       string          `json:"output,omitempty"` // Revert data of the frame
+	Depth        int             `json:"depth"`
+	Transfers    []TokenTransfer `json:"transfers,omitempty"`
+}
+
+// TokenTransfer represents a summarized asset change
+type TokenTransfer struct {
+	Type   string `json:"type"`
+	Token  string `json:"token"`
+	Symbol string `json:"symbol,omitempty"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Amount string `json:"amount"`
+}
+
+// SwapSimulation represents the outcome of the swap in a simulated bundle
+type SwapSimulation struct {
+	Success      bool
+	ErrorMessage string
+	URL          string
+	RevertData   string          // Hex encoded output of the reverted swap
+	ErrorAddress string          // Contract that raised the error, when Tenderly reports it
+	GasUsed      uint64          // Gas used by the swap, 0 when the backend does not report it
+	Failure      *FailureSummary // Set for failed swaps once the full trace is available
+}
@@ -0,0 +1,126 @@
+package tenderly
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// QuotaError is returned when Tenderly rejects a simulation for billing or
+// rate limit reasons, or when the configured daily limit is reached
+type QuotaError struct {
+	StatusCode int // 0 when the local daily limit was hit
+	Message    string
+}
+
+func (e *QuotaError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("Tenderly quota exhausted: %s", e.Message)
+	}
+	return fmt.Sprintf("Tenderly quota exhausted (status %d): %s", e.StatusCode, e.Message)
+}
+
+// Usage represents the simulation counts of the client
+type Usage struct {
+	Run        int `json:"run"`         // Simulations since the last ResetRunUsage
+	Today      int `json:"today"`       // Simulations on the current UTC day
+	DailyLimit int `json:"daily_limit"` // 0 when unlimited
+}
+
+// usageState is the daily count persisted in the usage file
+type usageState struct {
+	Day   string `json:"day"`
+	Count int    `json:"count"`
+}
+
+// usageTracker counts simulations per run and per UTC day
+type usageTracker struct {
+	mu         sync.Mutex
+	file       string
+	dailyLimit int
+	run        int
+	day        usageState
+}
+
+// newUsageTracker creates a tracker, loading today's count from file when set
+func newUsageTracker(file string, dailyLimit int) *usageTracker {
+	t := &usageTracker{file: file, dailyLimit: dailyLimit}
+	if file == "" {
+		return t
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return t
+	}
+	var state usageState
+	if err := json.Unmarshal(data, &state); err == nil {
+		t.day = state
+	}
+	return t
+}
+
+// reserve records count simulations, failing when they would exceed the daily limit
+func (t *usageTracker) reserve(count int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	today := time.Now().UTC().Format("2006-01-02")
+	if t.day.Day != today {
+		t.day = usageState{Day: today}
+	}
+
+	if t.dailyLimit > 0 && t.day.Count+count > t.dailyLimit {
+		return &QuotaError{Message: fmt.Sprintf("daily limit of %d simulations reached", t.dailyLimit)}
+	}
+
+	t.run += count
+	t.day.Count += count
+
+	// Best effort, a lost count only loosens the daily limit
+	_ = t.save()
+	return nil
+}
+
+// save writes the daily count to the usage file
+func (t *usageTracker) save() error {
+	if t.file == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(t.file), 0755); err != nil {
+		return fmt.Errorf("failed to create usage directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(t.day, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage: %v", err)
+	}
+
+	if err := os.WriteFile(t.file, data, 0644); err != nil {
+		return fmt.Errorf("failed to write usage file: %v", err)
+	}
+	return nil
+}
+
+// ResetRunUsage starts counting the simulations of a new run
+func (c *Client) ResetRunUsage() {
+	c.usage.mu.Lock()
+	defer c.usage.mu.Unlock()
+	c.usage.run = 0
+}
+
+// Usage returns the simulation counts of the current run and day
+func (c *Client) Usage() Usage {
+	c.usage.mu.Lock()
+	defer c.usage.mu.Unlock()
+
+	usage := Usage{Run: c.usage.run, DailyLimit: c.usage.dailyLimit}
+	if c.usage.day.Day == time.Now().UTC().Format("2006-01-02") {
+		usage.Today = c.usage.day.Count
+	}
+	return usage
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -12,6 +13,7 @@ import (
 	"scale-helper-monitor/internal/clients/slack"
 	"scale-helper-monitor/internal/clients/tenderly"
 	"scale-helper-monitor/internal/monitor"
+	"scale-helper-monitor/internal/report"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
@@ -28,18 +30,26 @@ type Config struct {
 	Tokens            map[string]map[string]monitor.TokenInfo `mapstructure:"tokens"`            // chain name -> token address -> token info
 	Sources           map[string][]string                     `mapstructure:"liquidity_sources"` // chain name -> available sources
 	OnlyScaleDownDexs []string                                `mapstructure:"only_scale_down_dexs"`
+	Reports           report.Config                           `mapstructure:"reports"`
+	Alerts            slack.TemplateConfig                    `mapstructure:"alerts"`
 }
 
 // SlackConfig represents Slack configuration
 type SlackConfig struct {
-	WebhookURL string `mapstructure:"webhook_url"`
+	WebhookURL      string `mapstructure:"webhook_url"`
+	Token           string `mapstructure:"token"`             // Bot token, switches alerts to threaded mode
+	Channel         string `mapstructure:"channel"`           // Channel for threaded alerts
+	ThreadStateFile string `mapstructure:"thread_state_file"` // Keeps the open alert thread between runs
 }
 
 // TenderlyConfig represents Tenderly configuration
 type TenderlyConfig struct {
-	AccessKey string `mapstructure:"access_key"`
-	Username  string `mapstructure:"username"`
-	Project   string `mapstructure:"project"`
+	AccessKey        string `mapstructure:"access_key"`
+	Username         string `mapstructure:"username"`
+	Project          string `mapstructure:"project"`
+	SaveOnlyFailures bool   `mapstructure:"save_only_failures"` // Keep only failing simulations in the project
+	DailyLimit       int    `mapstructure:"daily_limit"`        // Simulations per UTC day, 0 for unlimited
+	UsageFile        string `mapstructure:"usage_file"`         // Persists the daily simulation count
 }
 
 // KyberSwapDexResponse represents the API response structure
@@ -61,9 +71,18 @@ type KyberSwapDexResponse struct {
 	} `json:"data"`
 }
 
-// GetSlackClient creates a Slack client from the configuration
-func (c *Config) GetSlackClient(timeout time.Duration, logger *logrus.Logger) *slack.Client {
-	return slack.NewClient(c.Slack.WebhookURL, timeout, logger)
+// GetSlackClient creates a Slack client from the configuration. A bot token
+// takes precedence over the webhook URL.
+func (c *Config) GetSlackClient(timeout time.Duration, logger *logrus.Logger) (*slack.Client, error) {
+	templates, err := slack.NewTemplates(c.Alerts)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Slack.Token != "" {
+		return slack.NewBotClient(c.Slack.Token, c.Slack.Channel, c.Slack.ThreadStateFile, templates, timeout, logger), nil
+	}
+	return slack.NewClient(c.Slack.WebhookURL, templates, timeout, logger), nil
 }
 
 // GetKyberSwapClient creates a KyberSwap client from the configuration
@@ -73,17 +92,28 @@ func (c *Config) GetKyberSwapClient(timeout time.Duration, logger *logrus.Logger
 
 // GetTenderlyClient creates a Tenderly client from the configuration
 func (c *Config) GetTenderlyClient(timeout time.Duration) *tenderly.Client {
-	return tenderly.NewClient(c.Tenderly.AccessKey, c.Tenderly.Username, c.Tenderly.Project, timeout)
+	options := tenderly.Options{
+		SaveOnlyFailures: c.Tenderly.SaveOnlyFailures,
+		DailyLimit:       c.Tenderly.DailyLimit,
+		UsageFile:        c.Tenderly.UsageFile,
+	}
+	return tenderly.NewClient(c.Tenderly.AccessKey, c.Tenderly.Username, c.Tenderly.Project, options, timeout)
 }
 
-// Load loads configuration from file and environment variables
-func Load() (*Config, error) {
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath(".")
-	viper.AddConfigPath("./configs")
-	viper.AddConfigPath("$HOME/.scale-helper-monitor")
-	viper.AddConfigPath("/etc/scale-helper-monitor/")
+// Load loads configuration from file and environment variables.
+// An empty configPath searches the default locations for config.yaml and an
+// empty tokensPath reads ./tokens.json.
+func Load(configPath, tokensPath string) (*Config, error) {
+	if configPath != "" {
+		viper.SetConfigFile(configPath)
+	} else {
+		viper.SetConfigName("config")
+		viper.SetConfigType("yaml")
+		viper.AddConfigPath(".")
+		viper.AddConfigPath("./configs")
+		viper.AddConfigPath("$HOME/.scale-helper-monitor")
+		viper.AddConfigPath("/etc/scale-helper-monitor/")
+	}
 
 	// Enable environment variable substitution
 	viper.AutomaticEnv()
@@ -99,91 +129,155 @@ func Load() (*Config, error) {
 
 	// Slack config
 	config.Slack.WebhookURL = os.Getenv("SLACK_WEBHOOK_URL")
+	config.Slack.Token = os.Getenv("SCALE_HELPER_SLACK_TOKEN")
+	config.Slack.Channel = os.Getenv("SCALE_HELPER_SLACK_CHANNEL")
+	config.Slack.ThreadStateFile = viper.GetString("slack.thread_state_file")
+
+	// Alert templates
+	config.Alerts.Timezone = viper.GetString("alerts.timezone")
+	config.Alerts.Title = viper.GetString("alerts.title")
+	config.Alerts.Summary = viper.GetString("alerts.summary")
+	config.Alerts.Failure = viper.GetString("alerts.failure")
 
 	// Tenderly config
 	config.Tenderly.AccessKey = os.Getenv("TENDERLY_ACCESS_KEY")
 	config.Tenderly.Username = os.Getenv("TENDERLY_USERNAME")
 	config.Tenderly.Project = os.Getenv("TENDERLY_PROJECT")
+	config.Tenderly.SaveOnlyFailures = viper.GetBool("tenderly.save_only_failures")
+	config.Tenderly.DailyLimit = viper.GetInt("tenderly.daily_limit")
+	config.Tenderly.UsageFile = viper.GetString("tenderly.usage_file")
 
 	// Monitoring config
 	config.Monitoring.Interval = viper.GetString("monitoring.interval")
 	config.Monitoring.Timeout = viper.GetString("monitoring.timeout")
+	config.Monitoring.HistoryFile = viper.GetString("monitoring.history_file")
+	config.Monitoring.MaxHistoryRuns = viper.GetInt("monitoring.max_history_runs")
+	config.Monitoring.ErrorABIFiles = viper.GetStringSlice("monitoring.error_abi_files")
+	config.Monitoring.FallbackSimulator = viper.GetString("monitoring.fallback_simulator")
+	config.Monitoring.Traffic.RouterAddress = viper.GetString("monitoring.traffic.router_address")
+	config.Monitoring.Traffic.Blocks = viper.GetUint64("monitoring.traffic.blocks")
+	config.Monitoring.Traffic.FromBlock = viper.GetUint64("monitoring.traffic.from_block")
+	config.Monitoring.Traffic.ToBlock = viper.GetUint64("monitoring.traffic.to_block")
+	config.Monitoring.Traffic.MaxSamples = viper.GetInt("monitoring.traffic.max_samples")
+	config.Monitoring.Traffic.MaxPerDex = viper.GetInt("monitoring.traffic.max_per_dex")
+	config.Monitoring.CorpusFile = viper.GetString("monitoring.corpus_file")
+	config.Monitoring.PropertyChecks = viper.GetStringSlice("monitoring.property_checks")
+	config.Monitoring.Deployments.ExpectedCodeHashes = viper.GetStringSlice("monitoring.deployments.expected_code_hashes")
+	config.Monitoring.Deployments.CompareChains = viper.GetBool("monitoring.deployments.compare_chains")
+	config.Monitoring.RPC.MaxHeadAge = viper.GetString("monitoring.rpc.max_head_age")
+	config.Monitoring.Gas.MaxSwapDivergencePct = viper.GetFloat64("monitoring.gas.max_swap_divergence_pct")
+	config.Monitoring.Gas.MaxHelperGrowthPct = viper.GetFloat64("monitoring.gas.max_helper_growth_pct")
+	config.Monitoring.EdgeScenarios.Enabled = viper.GetBool("monitoring.edge_scenarios.enabled")
+	config.Monitoring.EdgeScenarios.Expectations = viper.GetStringMapString("monitoring.edge_scenarios.expectations")
+	if err := viper.UnmarshalKey("monitoring.edge_scenarios.dex_overrides", &config.Monitoring.EdgeScenarios.DexOverrides); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal monitoring.edge_scenarios.dex_overrides: %w", err)
+	}
+	config.Monitoring.Encodings.StateFile = viper.GetString("monitoring.encodings.state_file")
+	config.Monitoring.Encodings.Selectors = viper.GetStringSlice("monitoring.encodings.selectors")
+	config.Monitoring.Encodings.Executors = viper.GetStringSlice("monitoring.encodings.executors")
+	config.Monitoring.Encodings.ExecutorEntries = viper.GetStringSlice("monitoring.encodings.executor_entries")
+	config.Monitoring.Encodings.ExecutorFunctions = viper.GetStringSlice("monitoring.encodings.executor_functions")
 
 	// KyberSwap config
 	config.KyberSwap.APIBaseURL = viper.GetString("kyberswap.api_base_url")
 	config.KyberSwap.ClientID = viper.GetString("kyberswap.client_id")
+	if err := viper.UnmarshalKey("kyberswap.environments", &config.KyberSwap.Environments); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal kyberswap.environments: %w", err)
+	}
 	config.OnlyScaleDownDexs = viper.GetStringSlice("only_scale_down_dexs")
 
+	// Reports config
+	config.Reports.JSONFile = viper.GetString("reports.json_file")
+	config.Reports.JUnitFile = viper.GetString("reports.junit_file")
+	config.Reports.MarkdownFile = viper.GetString("reports.markdown_file")
+	config.Reports.ExitPolicy.FailOnScaleFailure = viper.GetBool("reports.exit_policy.fail_on_scale_failure")
+	config.Reports.ExitPolicy.MaxInfraErrorRate = viper.GetFloat64("reports.exit_policy.max_infra_error_rate")
+
 	// Chains config - adding all supported chains from tokens.json
 	config.Chains = []monitor.ChainConfig{
 		{
 			Name:            "ethereum",
 			ChainID:         1,
-			RPCURL:          os.Getenv("ETH_NODE_URL"),
+			RPCURLs:         nodeURLs("ETH_NODE_URL"),
 			ContractAddress: os.Getenv("CONTRACT_ADDRESS"),
 		},
 		{
 			Name:            "polygon",
 			ChainID:         137,
-			RPCURL:          os.Getenv("POLYGON_NODE_URL"),
+			RPCURLs:         nodeURLs("POLYGON_NODE_URL"),
 			ContractAddress: os.Getenv("CONTRACT_ADDRESS"),
 		},
 		{
 			Name:            "bsc",
 			ChainID:         56,
-			RPCURL:          os.Getenv("BSC_NODE_URL"),
+			RPCURLs:         nodeURLs("BSC_NODE_URL"),
 			ContractAddress: os.Getenv("CONTRACT_ADDRESS"),
 		},
 		{
 			Name:            "arbitrum",
 			ChainID:         42161,
-			RPCURL:          os.Getenv("ARBITRUM_NODE_URL"),
+			RPCURLs:         nodeURLs("ARBITRUM_NODE_URL"),
 			ContractAddress: os.Getenv("CONTRACT_ADDRESS"),
 		},
 		{
 			Name:            "avalanche",
 			ChainID:         43114,
-			RPCURL:          os.Getenv("AVAX_NODE_URL"),
+			RPCURLs:         nodeURLs("AVAX_NODE_URL"),
 			ContractAddress: os.Getenv("CONTRACT_ADDRESS"),
 		},
 		{
 			Name:            "base",
 			ChainID:         8453,
-			RPCURL:          os.Getenv("BASE_NODE_URL"),
+			RPCURLs:         nodeURLs("BASE_NODE_URL"),
 			ContractAddress: os.Getenv("CONTRACT_ADDRESS"),
 		},
 		{
 			Name:            "berachain",
 			ChainID:         80094,
-			RPCURL:          os.Getenv("BERA_NODE_URL"),
+			RPCURLs:         nodeURLs("BERA_NODE_URL"),
 			ContractAddress: os.Getenv("CONTRACT_ADDRESS"),
 		},
 		{
 			Name:            "mantle",
 			ChainID:         5000,
-			RPCURL:          os.Getenv("MANTLE_NODE_URL"),
+			RPCURLs:         nodeURLs("MANTLE_NODE_URL"),
 			ContractAddress: os.Getenv("CONTRACT_ADDRESS"),
 		},
 		{
 			Name:            "optimism",
 			ChainID:         10,
-			RPCURL:          os.Getenv("OPTIMISM_NODE_URL"),
+			RPCURLs:         nodeURLs("OPTIMISM_NODE_URL"),
 			ContractAddress: os.Getenv("CONTRACT_ADDRESS"),
 		},
 		{
 			Name:            "sonic",
 			ChainID:         146,
-			RPCURL:          os.Getenv("SONIC_NODE_URL"),
+			RPCURLs:         nodeURLs("SONIC_NODE_URL"),
 			ContractAddress: os.Getenv("CONTRACT_ADDRESS"),
 		},
 		{
 			Name:            "unichain",
 			ChainID:         1301,
-			RPCURL:          os.Getenv("UNICHAIN_NODE_URL"),
+			RPCURLs:         nodeURLs("UNICHAIN_NODE_URL"),
 			ContractAddress: os.Getenv("CONTRACT_ADDRESS"),
 		},
 	}
 
+	// Other scale helper deployments compared with CONTRACT_ADDRESS, e.g. a candidate
+	// before an upgrade. CANDIDATE_CONTRACT_ADDRESS applies to every chain.
+	for i := range config.Chains {
+		helpers := viper.GetStringMapString("scale_helpers." + config.Chains[i].Name)
+		if candidate := os.Getenv("CANDIDATE_CONTRACT_ADDRESS"); candidate != "" {
+			if helpers == nil {
+				helpers = make(map[string]string)
+			}
+			helpers["candidate"] = candidate
+		}
+		if len(helpers) > 0 {
+			config.Chains[i].Helpers = helpers
+		}
+	}
+
 	// Fetch liquidity sources for each chain
 	config.Sources = make(map[string][]string)
 	// Parse timeout for clients
@@ -201,7 +295,7 @@ func Load() (*Config, error) {
 	}
 
 	// Load tokens from JSON file
-	tokens, err := loadTokens()
+	tokens, err := loadTokens(tokensPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load tokens: %w", err)
 	}
@@ -217,13 +311,14 @@ func Load() (*Config, error) {
 	return &config, nil
 }
 
-func loadTokens() (map[string]map[string]monitor.TokenInfo, error) {
-	// Try to read from multiple possible locations
-	path := "./tokens.json"
+func loadTokens(path string) (map[string]map[string]monitor.TokenInfo, error) {
+	if path == "" {
+		path = "./tokens.json"
+	}
 	data, err := os.ReadFile(path)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to read tokens.json from any location: %w", err)
+		return nil, fmt.Errorf("failed to read tokens from %s: %w", path, err)
 	}
 
 	// First, unmarshal into the nested structure as it exists in the JSON file
@@ -235,6 +330,56 @@ func loadTokens() (map[string]map[string]monitor.TokenInfo, error) {
 	return tokens, nil
 }
 
+// nodeURLs reads the comma-separated RPC URLs of a chain, tried in order
+func nodeURLs(env string) []string {
+	var urls []string
+	for _, url := range strings.Split(os.Getenv(env), ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+// Validate checks the loaded test cases against tokens, chains and liquidity sources
+func (c *Config) Validate() error {
+	errs := monitor.ValidateTestCases(c.TestCases, c.Tokens, c.Chains, c.Sources)
+	if c.Slack.Token != "" && c.Slack.Channel == "" {
+		errs = append(errs, fmt.Errorf("SCALE_HELPER_SLACK_CHANNEL is required when SCALE_HELPER_SLACK_TOKEN is set"))
+	}
+	if _, err := slack.NewTemplates(c.Alerts); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Monitoring.Encodings.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Monitoring.Deployments.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Monitoring.EdgeScenarios.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Monitoring.RPC.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	environments := map[string]bool{kyberswap.DefaultEnvironment: true}
+	for _, env := range c.KyberSwap.Environments {
+		if env.Name == "" || env.APIBaseURL == "" || environments[env.Name] {
+			errs = append(errs, fmt.Errorf("aggregator environment %q needs a unique name and an api_base_url", env.Name))
+		}
+		environments[env.Name] = true
+	}
+	for _, testCase := range c.TestCases {
+		for _, env := range testCase.Environments {
+			if !environments[env] {
+				errs = append(errs, fmt.Errorf("%s: unknown aggregator environment %s", monitor.TestCaseKey(testCase, c.Tokens), env))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func loadTestCases() ([]monitor.TestCase, error) {
 	// Load the nested test cases structure
 	var nestedTestCases map[string][]monitor.TestCase
@@ -0,0 +1,76 @@
+package monitor
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"scale-helper-monitor/internal/clients/rpcpool"
+)
+
+// ContractCaller is the chain access the scale checks need. *rpcpool.Pool
+// implements it against live nodes, the chaintest package without any.
+type ContractCaller interface {
+	ChainID(ctx context.Context) (*big.Int, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+	EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error)
+}
+
+// CodeReader reads contract code and storage, for the deployment checks
+type CodeReader interface {
+	CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error)
+	StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error)
+}
+
+// BlockReader finds mined transactions through their logs, for traffic sampling.
+// Whole blocks are not read, go-ethereum cannot decode the system transactions
+// of Arbitrum and OP-stack chains.
+type BlockReader interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error)
+	TransactionByHash(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+// healthChecker is implemented by clients spreading calls over several endpoints
+type healthChecker interface {
+	Check(ctx context.Context) []rpcpool.Health
+}
+
+// blockEstimator is implemented by clients that estimate gas at a given block
+type blockEstimator interface {
+	EstimateGasAt(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) (uint64, error)
+}
+
+// blockPinner is implemented by clients spreading calls over several endpoints,
+// it binds the calls made at a block to the endpoint that served its header
+type blockPinner interface {
+	Pin(ctx context.Context, number *big.Int) (*rpcpool.Pinned, *types.Header, error)
+}
+
+// pinBlock fetches a block header, the latest one for a nil number, and returns
+// it with a client whose calls go to the endpoint that served it
+func pinBlock(ctx context.Context, client ContractCaller, number *big.Int) (*types.Header, ContractCaller, error) {
+	if pinner, ok := client.(blockPinner); ok {
+		pinned, header, err := pinner.Pin(ctx, number)
+		if err != nil {
+			return nil, nil, err
+		}
+		return header, pinned, nil
+	}
+	header, err := client.HeaderByNumber(ctx, number)
+	return header, client, err
+}
+
+// servedBy returns the RPC endpoint that served the last call of a client,
+// empty for clients that do not report it
+func servedBy(client ContractCaller) string {
+	if reporter, ok := client.(interface{ Served() string }); ok {
+		return reporter.Served()
+	}
+	return ""
+}
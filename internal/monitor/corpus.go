@@ -0,0 +1,274 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// DefaultCorpusFile is used when monitoring.corpus_file is not configured
+const DefaultCorpusFile = "corpus/golden.json"
+
+// CorpusEntry represents a getScaledInputData input with the output of a known-good run
+type CorpusEntry struct {
+	ID              string    `json:"id"`
+	ChainName       string    `json:"chain_name"`
+	BlockNumber     uint64    `json:"block_number"`
+	Case            string    `json:"case,omitempty"`
+	InputData       string    `json:"input_data"`
+	NewAmount       string    `json:"new_amount"`
+	Helper          string    `json:"helper"` // Helper the expected output was captured from
+	ExpectedSuccess bool      `json:"expected_success"`
+	ExpectedData    string    `json:"expected_data"`
+	SourceResult    string    `json:"source_result,omitempty"`
+	CapturedAt      time.Time `json:"captured_at"`
+}
+
+// Corpus is the checked-in list of corpus entries
+type Corpus struct {
+	path    string
+	Entries []CorpusEntry
+}
+
+// LoadCorpus reads a corpus file, a missing file is an empty corpus
+func LoadCorpus(path string) (*Corpus, error) {
+	corpus := &Corpus{path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return corpus, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read corpus: %v", err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return corpus, nil
+	}
+	if err := json.Unmarshal(data, &corpus.Entries); err != nil {
+		return nil, fmt.Errorf("failed to parse corpus: %v", err)
+	}
+	return corpus, nil
+}
+
+// Add appends the entries that are not in the corpus yet and returns how many were added
+func (c *Corpus) Add(entries ...CorpusEntry) int {
+	known := make(map[string]bool, len(c.Entries))
+	for _, entry := range c.Entries {
+		known[entry.ID] = true
+	}
+
+	added := 0
+	for _, entry := range entries {
+		if known[entry.ID] {
+			continue
+		}
+		known[entry.ID] = true
+		c.Entries = append(c.Entries, entry)
+		added++
+	}
+	return added
+}
+
+// Save writes the corpus back to its file
+func (c *Corpus) Save() error {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create corpus directory: %v", err)
+	}
+	data, err := json.MarshalIndent(c.Entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal corpus: %v", err)
+	}
+	if err := os.WriteFile(c.path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write corpus: %v", err)
+	}
+	return nil
+}
+
+// corpusEntryID identifies an input by chain, block, calldata and new amount
+func corpusEntryID(chainName string, blockNumber uint64, inputData, newAmount string) string {
+	key := fmt.Sprintf("%s/%d/%s/%s", chainName, blockNumber, strings.ToLower(inputData), newAmount)
+	return crypto.Keccak256Hash([]byte(key)).Hex()[:18]
+}
+
+// CorpusEntries turns passing results into corpus entries. Results that failed,
+// hit an error or lack the block, input or output are skipped, and so are edge
+// scenarios, which pass when the helper rejects the amount gracefully.
+func (m *Monitor) CorpusEntries(results []*Result) []CorpusEntry {
+	now := time.Now().UTC()
+	var entries []CorpusEntry
+	for _, result := range results {
+		if !result.IsSuccess || result.FailureType != "" || result.Scenario != "" || result.BlockNumber == 0 ||
+			result.InputData == "" || result.NewAmount == "" || result.ReturnedData == "" {
+			continue
+		}
+		chainConfig := m.findChain(result.ChainName)
+		if chainConfig == nil {
+			continue
+		}
+
+		entries = append(entries, CorpusEntry{
+			ID:              corpusEntryID(result.ChainName, result.BlockNumber, result.InputData, result.NewAmount),
+			ChainName:       result.ChainName,
+			BlockNumber:     result.BlockNumber,
+			Case:            result.Case,
+			InputData:       result.InputData,
+			NewAmount:       result.NewAmount,
+			Helper:          chainConfig.ContractAddress,
+			ExpectedSuccess: true,
+			ExpectedData:    result.ReturnedData,
+			SourceResult:    result.ID,
+			CapturedAt:      now,
+		})
+	}
+	return entries
+}
+
+// CorpusCheck represents the outcome of re-running one corpus entry
+type CorpusCheck struct {
+	Entry       CorpusEntry
+	Helper      string
+	BlockNumber uint64
+	IsSuccess   bool
+	Data        string
+	Error       string
+	Matches     bool
+	Diff        string
+}
+
+// VerifyCorpus re-runs each entry with eth_call and compares the output with the
+// expected one. An empty helper uses the chain's CONTRACT_ADDRESS. A zero
+// blockNumber uses the entry's own block, or the latest block when the helper
+// is not the one the entry was captured with, which may have had no code then.
+func (m *Monitor) VerifyCorpus(ctx context.Context, entries []CorpusEntry, helper string, blockNumber uint64) []CorpusCheck {
+	checks := make([]CorpusCheck, 0, len(entries))
+	latest := make(map[string]uint64)
+	for _, entry := range entries {
+		check := CorpusCheck{Entry: entry, Helper: helper, BlockNumber: blockNumber}
+
+		chainConfig := m.findChain(entry.ChainName)
+		client, exists := m.ethClients[entry.ChainName]
+		if chainConfig == nil || !exists {
+			check.Error = fmt.Sprintf("chain %s is not configured or has no RPC client", entry.ChainName)
+			checks = append(checks, check)
+			continue
+		}
+		if check.Helper == "" {
+			check.Helper = chainConfig.ContractAddress
+		}
+		if check.BlockNumber == 0 {
+			check.BlockNumber = entry.BlockNumber
+			if !strings.EqualFold(check.Helper, entry.Helper) {
+				if latest[entry.ChainName] == 0 {
+					header, err := client.HeaderByNumber(ctx, nil)
+					if err != nil {
+						check.Error = fmt.Sprintf("failed to get latest block: %v", err)
+						checks = append(checks, check)
+						continue
+					}
+					latest[entry.ChainName] = header.Number.Uint64()
+				}
+				check.BlockNumber = latest[entry.ChainName]
+			}
+		}
+
+		inputData, err := hexutil.Decode(entry.InputData)
+		if err != nil {
+			check.Error = fmt.Sprintf("invalid input data: %v", err)
+			checks = append(checks, check)
+			continue
+		}
+		newAmount, ok := new(big.Int).SetString(entry.NewAmount, 10)
+		if !ok {
+			check.Error = fmt.Sprintf("invalid new amount: %s", entry.NewAmount)
+			checks = append(checks, check)
+			continue
+		}
+
+		result, err := m.callGetScaledInputData(ctx, client, check.Helper, inputData, newAmount, new(big.Int).SetUint64(check.BlockNumber))
+		if err != nil {
+			check.Error = err.Error()
+			checks = append(checks, check)
+			continue
+		}
+
+		check.IsSuccess = result.IsSuccess
+		check.Data = hexutil.Encode(result.Data)
+		expected, _ := hexutil.Decode(entry.ExpectedData)
+		check.Matches = result.IsSuccess == entry.ExpectedSuccess && bytes.Equal(result.Data, expected)
+		if !check.Matches {
+			check.Diff = calldataDiff(entry.ExpectedSuccess, expected, result.IsSuccess, result.Data)
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// calldataDiff describes where the returned output departs from the expected one
+func calldataDiff(expectedSuccess bool, expected []byte, isSuccess bool, actual []byte) string {
+	if expectedSuccess != isSuccess {
+		return fmt.Sprintf("isSuccess %t, expected %t", isSuccess, expectedSuccess)
+	}
+
+	offset := 0
+	for offset < len(expected) && offset < len(actual) && expected[offset] == actual[offset] {
+		offset++
+	}
+
+	// Show the selector or the 32-byte argument word holding the first difference
+	start, end := 0, 4
+	diff := fmt.Sprintf("first difference at byte %d", offset)
+	if offset >= 4 {
+		index := (offset - 4) / 32
+		start, end = 4+index*32, 4+(index+1)*32
+		diff = fmt.Sprintf("%s (word %d)", diff, index)
+	}
+	if len(expected) != len(actual) {
+		diff = fmt.Sprintf("%s, length %d, expected %d", diff, len(actual), len(expected))
+	}
+	return fmt.Sprintf("%s: %s, expected %s", diff, dataWord(actual, start, end), dataWord(expected, start, end))
+}
+
+func dataWord(data []byte, start, end int) string {
+	if start >= len(data) {
+		return "-"
+	}
+	if end > len(data) {
+		end = len(data)
+	}
+	return hexutil.Encode(data[start:end])
+}
+
+// PrintCorpusChecks writes one line per corpus entry and returns the number of
+// entries that diverge or could not be run
+func PrintCorpusChecks(w io.Writer, checks []CorpusCheck) int {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENTRY\tCHAIN\tBLOCK\tHELPER\tOUTCOME")
+
+	failed := 0
+	for _, check := range checks {
+		outcome := "match"
+		switch {
+		case check.Error != "":
+			outcome = fmt.Sprintf("error: %s", check.Error)
+			failed++
+		case !check.Matches:
+			outcome = fmt.Sprintf("diff: %s", check.Diff)
+			failed++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", check.Entry.ID, check.Entry.ChainName, check.BlockNumber, check.Helper, outcome)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\n%d entries, %d match, %d diverge or failed\n", len(checks), len(checks)-failed, failed)
+	return failed
+}
@@ -0,0 +1,272 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sirupsen/logrus"
+)
+
+// eip1967ImplementationSlot is bytes32(uint256(keccak256("eip1967.proxy.implementation")) - 1)
+var eip1967ImplementationSlot = common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+
+// DeploymentConfig lists the scale helper bytecode known to be good
+type DeploymentConfig struct {
+	ExpectedCodeHashes []string `mapstructure:"expected_code_hashes"` // keccak256 of the runtime code, of the implementation for proxies
+	CompareChains      bool     `mapstructure:"compare_chains"`       // Without expected hashes, report current helpers whose code differs across chains
+}
+
+// Validate checks the expected hashes are 32-byte hex values
+func (c DeploymentConfig) Validate() error {
+	var errs []error
+	for _, hash := range c.ExpectedCodeHashes {
+		if decoded := common.FromHex(hash); len(decoded) != common.HashLength || !strings.HasPrefix(hash, "0x") {
+			errs = append(errs, fmt.Errorf("invalid expected code hash %q", hash))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DeploymentCheck records the code found at one scale helper address
+type DeploymentCheck struct {
+	ChainName          string    `json:"chain_name"`
+	Label              string    `json:"label"` // CurrentHelper or the label in ChainConfig.Helpers
+	Address            string    `json:"address"`
+	CodeHash           string    `json:"code_hash,omitempty"`
+	Implementation     string    `json:"implementation,omitempty"` // EIP-1967 implementation, for proxies
+	ImplementationHash string    `json:"implementation_hash,omitempty"`
+	Error              string    `json:"error,omitempty"`    // RPC error, the code could not be read
+	Problems           []string  `json:"problems,omitempty"` // Missing code, unexpected or changed bytecode
+	CheckedAt          time.Time `json:"checked_at"`
+}
+
+// effectiveHash is the hash of the code that runs, the implementation's for proxies
+func (d *DeploymentCheck) effectiveHash() string {
+	if d.Implementation != "" {
+		return d.ImplementationHash
+	}
+	return d.CodeHash
+}
+
+func (d *DeploymentCheck) key() string {
+	return d.ChainName + "/" + d.Label
+}
+
+// CheckDeployments reads the code of every scale helper deployment, compares it
+// with the expected hashes, across chains and with the previous check, and
+// sends a Slack notice for the problems found. Only problems the previous
+// check, of this process or the latest run in the history, did not report are sent.
+func (m *Monitor) CheckDeployments(ctx context.Context) []DeploymentCheck {
+	previous := m.previousDeployments()
+
+	var checks []DeploymentCheck
+	for i := range m.chains {
+		chainConfig := &m.chains[i]
+		client, ok := m.ethClients[chainConfig.Name].(CodeReader)
+		if !ok {
+			continue
+		}
+
+		helpers := map[string]string{CurrentHelper: chainConfig.ContractAddress}
+		for label, address := range chainConfig.Helpers {
+			helpers[label] = address
+		}
+		labels := make([]string, 0, len(helpers))
+		for label := range helpers {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+
+		for _, label := range labels {
+			if helpers[label] == "" {
+				continue
+			}
+			checks = append(checks, m.checkDeployment(ctx, client, chainConfig.Name, label, helpers[label]))
+		}
+	}
+
+	m.compareDeployments(checks, previous)
+	if checks == nil {
+		checks = []DeploymentCheck{}
+	}
+	m.deployments = checks
+	m.sendDeploymentNotice(checks, previous)
+	return checks
+}
+
+// checkDeployment reads the code and EIP-1967 implementation of a helper at the latest block
+func (m *Monitor) checkDeployment(ctx context.Context, client CodeReader, chainName, label, address string) DeploymentCheck {
+	check := DeploymentCheck{ChainName: chainName, Label: label, Address: address, CheckedAt: time.Now().UTC()}
+	contractAddr := common.HexToAddress(address)
+
+	code, err := client.CodeAt(ctx, contractAddr, nil)
+	if err != nil {
+		check.Error = fmt.Sprintf("failed to read code: %v", err)
+		return check
+	}
+	if len(code) == 0 {
+		check.Problems = append(check.Problems, "no code at the helper address")
+		return check
+	}
+	check.CodeHash = crypto.Keccak256Hash(code).Hex()
+
+	slot, err := client.StorageAt(ctx, contractAddr, eip1967ImplementationSlot, nil)
+	if err != nil {
+		check.Error = fmt.Sprintf("failed to read the implementation slot: %v", err)
+		return check
+	}
+	implementation := common.BytesToAddress(slot)
+	if implementation == (common.Address{}) {
+		return check
+	}
+	check.Implementation = implementation.Hex()
+
+	implementationCode, err := client.CodeAt(ctx, implementation, nil)
+	if err != nil {
+		check.Error = fmt.Sprintf("failed to read implementation code: %v", err)
+		return check
+	}
+	if len(implementationCode) == 0 {
+		check.Problems = append(check.Problems, fmt.Sprintf("no code at implementation %s", check.Implementation))
+		return check
+	}
+	check.ImplementationHash = crypto.Keccak256Hash(implementationCode).Hex()
+	return check
+}
+
+// compareDeployments adds the problems found by comparing the checks with the
+// expected hashes, the current helpers of other chains and the previous checks.
+// A change to an expected hash is not a problem.
+func (m *Monitor) compareDeployments(checks []DeploymentCheck, previous map[string]DeploymentCheck) {
+	expected := make(map[string]bool)
+	for _, hash := range m.config.Deployments.ExpectedCodeHashes {
+		expected[strings.ToLower(hash)] = true
+	}
+
+	// Chains running each bytecode of the current helper
+	chainsByHash := make(map[string][]string)
+	for _, check := range checks {
+		if check.Label == CurrentHelper && check.effectiveHash() != "" {
+			chainsByHash[check.effectiveHash()] = append(chainsByHash[check.effectiveHash()], check.ChainName)
+		}
+	}
+
+	for i := range checks {
+		check := &checks[i]
+		hash := check.effectiveHash()
+		if hash == "" {
+			continue
+		}
+		known := expected[strings.ToLower(hash)]
+		if len(expected) > 0 && !known {
+			check.Problems = append(check.Problems, fmt.Sprintf("code hash %s is not expected", hash))
+		}
+
+		// Without an expected list, the current helper may be required to run the same code everywhere
+		if m.config.Deployments.CompareChains && len(expected) == 0 && check.Label == CurrentHelper && len(chainsByHash) > 1 {
+			var others []string
+			for otherHash, chains := range chainsByHash {
+				if otherHash != hash {
+					others = append(others, chains...)
+				}
+			}
+			sort.Strings(others)
+			check.Problems = append(check.Problems, fmt.Sprintf("code hash %s differs from %s", hash, strings.Join(others, ", ")))
+		}
+
+		last, exists := previous[check.key()]
+		if !exists || known || !strings.EqualFold(last.Address, check.Address) {
+			continue
+		}
+		if last.Implementation != check.Implementation {
+			check.Problems = append(check.Problems, fmt.Sprintf("implementation changed from %s to %s", orNone(last.Implementation), orNone(check.Implementation)))
+		} else if last.effectiveHash() != "" && last.effectiveHash() != hash {
+			check.Problems = append(check.Problems, fmt.Sprintf("bytecode changed from %s to %s", last.effectiveHash(), hash))
+		}
+	}
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
+
+// previousDeployments returns the last deployment checks, from this process or
+// the latest run in the history
+func (m *Monitor) previousDeployments() map[string]DeploymentCheck {
+	checks := m.deployments
+	if checks == nil && m.history != nil {
+		runs, err := m.history.LoadRuns()
+		if err != nil {
+			m.logger.WithError(err).Warn("Failed to load history for deployment checks")
+		}
+		for i := len(runs) - 1; i >= 0 && checks == nil; i-- {
+			checks = runs[i].Deployments
+		}
+	}
+
+	previous := make(map[string]DeploymentCheck, len(checks))
+	for _, check := range checks {
+		if check.Error == "" {
+			previous[check.key()] = check
+		}
+	}
+	return previous
+}
+
+// helperMissing tells whether the last check found no code at the current helper of a chain
+func (m *Monitor) helperMissing(chainName string) bool {
+	for _, check := range m.deployments {
+		if check.ChainName == chainName && check.Label == CurrentHelper {
+			return check.Error == "" && check.CodeHash == ""
+		}
+	}
+	return false
+}
+
+// sendDeploymentNotice logs the deployment problems and posts the new ones to
+// Slack, unless running in dry-run mode
+func (m *Monitor) sendDeploymentNotice(checks []DeploymentCheck, previous map[string]DeploymentCheck) {
+	var lines []string
+	for _, check := range checks {
+		reported := make(map[string]bool)
+		for _, problem := range previous[check.key()].Problems {
+			reported[problem] = true
+		}
+		fields := logrus.Fields{
+			"chain":   check.ChainName,
+			"helper":  check.Label,
+			"address": check.Address,
+		}
+		if check.Error != "" {
+			m.logger.WithFields(fields).Warn(check.Error)
+		}
+		for _, problem := range check.Problems {
+			m.logger.WithFields(fields).Error(problem)
+			if reported[problem] {
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("%s helper %s `%s`: %s", check.ChainName, check.Label, check.Address, problem))
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+
+	if m.config.DryRun {
+		m.logger.WithField("problems", len(lines)).Info("Dry run, skipping Slack deployment alert")
+		return
+	}
+	title := fmt.Sprintf("🛡️ Scale Helper Monitor - %d deployment problems", len(lines))
+	if err := m.slackClient.SendNotice(title, lines); err != nil {
+		m.logger.WithError(err).Error("Failed to send Slack deployment alert")
+	}
+}
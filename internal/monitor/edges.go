@@ -0,0 +1,294 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Edge scenarios, each scales the test case input to a fixed amount
+const (
+	EdgeZero   = "zero"    // 0
+	EdgeOneWei = "one_wei" // 1 wei
+	EdgeExact  = "exact"   // The original amount
+	EdgeDouble = "double"  // 2x
+	EdgeTenX   = "ten_x"   // 10x
+)
+
+// edgeOrder is the order edge scenarios run and are reported in
+var edgeOrder = []string{EdgeZero, EdgeOneWei, EdgeExact, EdgeDouble, EdgeTenX}
+
+// Expected outcomes of an edge scenario
+const (
+	ExpectReject    = "reject"    // isSuccess=false, without reverting
+	ExpectGraceful  = "graceful"  // No revert, and a sane minReturn when isSuccess=true
+	ExpectScale     = "scale"     // isSuccess=true with a sane minReturn
+	ExpectIdentical = "identical" // isSuccess=true with the input calldata unchanged
+	ExpectSkip      = "skip"      // Not run
+)
+
+// defaultEdgeExpectations apply unless monitoring.edge_scenarios overrides them.
+// exact is skipped, the identity property check already scales to the original amount.
+var defaultEdgeExpectations = map[string]string{
+	EdgeZero:   ExpectReject,
+	EdgeOneWei: ExpectGraceful,
+	EdgeExact:  ExpectSkip,
+	EdgeDouble: ExpectScale,
+	EdgeTenX:   ExpectGraceful,
+}
+
+// EdgeConfig configures the edge scenarios run for every test case
+type EdgeConfig struct {
+	Enabled      bool                         `mapstructure:"enabled"`
+	Expectations map[string]string            `mapstructure:"expectations"`  // scenario -> expected outcome
+	DexOverrides map[string]map[string]string `mapstructure:"dex_overrides"` // dex -> scenario -> expected outcome
+}
+
+// Validate checks scenario and outcome names
+func (c EdgeConfig) Validate() error {
+	var errs []error
+	check := func(scope string, expectations map[string]string) {
+		for scenario, outcome := range expectations {
+			if _, exists := defaultEdgeExpectations[scenario]; !exists {
+				errs = append(errs, fmt.Errorf("%s: unknown edge scenario %q", scope, scenario))
+			}
+			switch outcome {
+			case ExpectReject, ExpectGraceful, ExpectScale, ExpectIdentical, ExpectSkip:
+			default:
+				errs = append(errs, fmt.Errorf("%s: unknown expected outcome %q for %s", scope, outcome, scenario))
+			}
+		}
+	}
+	check("edge_scenarios.expectations", c.Expectations)
+	for dex, expectations := range c.DexOverrides {
+		check("edge_scenarios.dex_overrides."+dex, expectations)
+	}
+	return errors.Join(errs...)
+}
+
+// edgeLenience ranks outcomes, the most lenient one wins when several dexes override a scenario
+var edgeLenience = map[string]int{
+	ExpectSkip:      0,
+	ExpectGraceful:  1,
+	ExpectReject:    2,
+	ExpectScale:     2,
+	ExpectIdentical: 2,
+}
+
+// edgeExpectations resolves the expected outcome of each scenario for a route
+func (m *Monitor) edgeExpectations(result *Result) map[string]string {
+	expectations := make(map[string]string, len(defaultEdgeExpectations))
+	for scenario, outcome := range defaultEdgeExpectations {
+		expectations[scenario] = outcome
+	}
+	for scenario, outcome := range m.config.EdgeScenarios.Expectations {
+		expectations[scenario] = outcome
+	}
+
+	// Dexes that cannot be scaled up only need to fail gracefully above the original amount
+	if !m.allowScalingUp(result.Route, m.onlyScaleDownDexs) {
+		for _, scenario := range []string{EdgeDouble, EdgeTenX} {
+			if edgeLenience[expectations[scenario]] > edgeLenience[ExpectGraceful] {
+				expectations[scenario] = ExpectGraceful
+			}
+		}
+	}
+
+	dexes := make([]string, 0, len(m.config.EdgeScenarios.DexOverrides))
+	for dex := range m.config.EdgeScenarios.DexOverrides {
+		dexes = append(dexes, dex)
+	}
+	sort.Strings(dexes)
+
+	overridden := make(map[string]bool)
+	for _, dex := range dexes {
+		if !m.allowScalingUp(result.Route, []string{dex}) { // The route goes through the dex
+			for scenario, outcome := range m.config.EdgeScenarios.DexOverrides[dex] {
+				if !overridden[scenario] || edgeLenience[outcome] < edgeLenience[expectations[scenario]] {
+					expectations[scenario] = outcome
+					overridden[scenario] = true
+				}
+			}
+		}
+	}
+	return expectations
+}
+
+// edgeAmount returns the amount a scenario scales to
+func edgeAmount(scenario string, original *big.Int) *big.Int {
+	switch scenario {
+	case EdgeZero:
+		return big.NewInt(0)
+	case EdgeOneWei:
+		return big.NewInt(1)
+	case EdgeDouble:
+		return new(big.Int).Mul(original, big.NewInt(2))
+	case EdgeTenX:
+		return new(big.Int).Mul(original, big.NewInt(10))
+	default:
+		return new(big.Int).Set(original)
+	}
+}
+
+// runEdgeScenarios calls the scale helper with each edge amount on the input of
+// a test case result, at the same block, and checks the expected outcome. It
+// returns one result per scenario that ran, failed ones carry a scale error.
+func (m *Monitor) runEdgeScenarios(ctx context.Context, base *Result) []*Result {
+	if !m.config.EdgeScenarios.Enabled || base.InputData == "" || base.BlockNumber == 0 {
+		return nil
+	}
+	chainConfig := m.findChain(base.ChainName)
+	client, exists := m.ethClients[base.ChainName]
+	if chainConfig == nil || !exists {
+		return nil
+	}
+
+	inputData, err := hexutil.Decode(base.InputData)
+	if err != nil {
+		return nil
+	}
+	original, err := decodeRouterCall(m.routerABI, inputData)
+	if err != nil {
+		m.logger.WithError(err).WithField("case", base.Case).Warn("Edge scenarios skipped, input is not a router swap")
+		return nil
+	}
+
+	expectations := m.edgeExpectations(base)
+	var results []*Result
+	for _, scenario := range edgeOrder {
+		expected := expectations[scenario]
+		if expected == ExpectSkip {
+			continue
+		}
+
+		startedAt := time.Now()
+		amount := edgeAmount(scenario, original.desc.Amount)
+		result := &Result{
+			Case:          base.Case,
+			ChainName:     base.ChainName,
+			TokenIn:       base.TokenIn,
+			TokenOut:      base.TokenOut,
+			Amount:        base.Amount,
+			Sender:        base.Sender,
+			SizeBucket:    base.SizeBucket,
+			Environment:   base.Environment,
+			Scenario:      scenario,
+			InputData:     base.InputData,
+			RouterAddress: base.RouterAddress,
+			BlockNumber:   base.BlockNumber,
+			Route:         base.Route,
+			NewAmount:     amount.String(),
+		}
+
+		err := m.checkEdgeScenario(ctx, client, chainConfig, result, inputData, original, amount, expected)
+		switch {
+		case err == nil:
+			result.IsSuccess = true
+		case errors.As(err, new(*CallGetScaledInputDataError)):
+			result.FailureType = FailureTypeScale
+			result.Error = err.Error()
+		default:
+			result.FailureType = FailureTypeInfra
+			result.Error = err.Error()
+		}
+		result.DurationMs = time.Since(startedAt).Milliseconds()
+		results = append(results, result)
+	}
+	return results
+}
+
+// checkEdgeScenario calls the helper for one scenario and compares the outcome
+// with the expected one
+func (m *Monitor) checkEdgeScenario(ctx context.Context, client ContractCaller, chainConfig *ChainConfig, result *Result, inputData []byte, original *routerCall, amount *big.Int, expected string) error {
+	unexpected := func(format string, args ...interface{}) error {
+		message := fmt.Sprintf("Edge %s expected %s: %s", result.Scenario, expected, fmt.Sprintf(format, args...))
+		return &CallGetScaledInputDataError{ChainName: chainConfig.Name, Message: message}
+	}
+
+	helper, err := m.callGetScaledInputData(ctx, client, chainConfig.ContractAddress, inputData, amount, new(big.Int).SetUint64(result.BlockNumber))
+	if err != nil {
+		var helperErr *CallGetScaledInputDataError
+		if !errors.As(err, &helperErr) {
+			return err
+		}
+		result.RevertReason = helperErr.Reason
+		return unexpected("helper reverted: %s", helperErr.Message)
+	}
+	result.ReturnedData = hexutil.Encode(helper.Data)
+	result.RPCEndpoint = helper.Endpoint
+
+	switch expected {
+	case ExpectReject:
+		if helper.IsSuccess {
+			return unexpected("helper returned isSuccess=true")
+		}
+		return nil
+	case ExpectScale, ExpectIdentical:
+		if !helper.IsSuccess {
+			return unexpected("helper returned isSuccess=false")
+		}
+	}
+	if !helper.IsSuccess {
+		return nil // Graceful failure
+	}
+
+	if expected == ExpectIdentical {
+		if !bytes.Equal(helper.Data, inputData) {
+			return unexpected("calldata changed, %s", calldataDiff(true, inputData, true, helper.Data))
+		}
+		return nil
+	}
+
+	scaled, err := decodeRouterCall(m.routerABI, helper.Data)
+	if err != nil {
+		return unexpected("scaled calldata is not a router swap: %v", err)
+	}
+	if problem := minReturnProblem(original.desc, scaled.desc, amount); problem != "" {
+		return unexpected("%s", problem)
+	}
+	return nil
+}
+
+// minReturnProblem checks the scaled amount and minReturn: minReturn must not be
+// negative as a signed word and must not exceed the original minReturn scaled
+// by the amount ratio, rounded up
+func minReturnProblem(original, scaled swapDescription, amount *big.Int) string {
+	if scaled.Amount.Cmp(amount) != 0 {
+		return fmt.Sprintf("amount %s, expected %s", scaled.Amount, amount)
+	}
+	if scaled.MinReturnAmount.Bit(255) == 1 {
+		return fmt.Sprintf("minReturn %s is negative as int256", scaled.MinReturnAmount)
+	}
+	if original.Amount.Sign() == 0 {
+		return ""
+	}
+
+	limit := new(big.Int).Mul(original.MinReturnAmount, amount)
+	limit.Add(limit, new(big.Int).Sub(original.Amount, big.NewInt(1)))
+	limit.Div(limit, original.Amount)
+	if scaled.MinReturnAmount.Cmp(limit) > 0 {
+		return fmt.Sprintf("minReturn %s overflows the proportional %s", scaled.MinReturnAmount, limit)
+	}
+	return ""
+}
+
+// edgeSummary describes the edge scenarios of a run for logs
+func edgeSummary(results []*Result) string {
+	var failed []string
+	for _, result := range results {
+		if result.FailureType != "" {
+			failed = append(failed, result.Scenario)
+		}
+	}
+	if len(failed) == 0 {
+		return "all edge scenarios passed"
+	}
+	return fmt.Sprintf("failed: %s", strings.Join(failed, ", "))
+}
@@ -0,0 +1,246 @@
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Kinds of router encoding elements checked for drift
+const (
+	EncodingSelector         = "selector"          // Router function selector
+	EncodingExecutor         = "executor"          // Executor the router hands the swap to
+	EncodingExecutorEntry    = "executor_entry"    // Executor function the router calls, swap mode only
+	EncodingExecutorFunction = "executor_function" // Executor function of a single swap
+)
+
+// EncodingConfig lists the router encodings the scale helper is known to support
+type EncodingConfig struct {
+	StateFile         string   `mapstructure:"state_file"` // Encodings seen so far, each new one alerts once
+	Selectors         []string `mapstructure:"selectors"`
+	Executors         []string `mapstructure:"executors"`
+	ExecutorEntries   []string `mapstructure:"executor_entries"`
+	ExecutorFunctions []string `mapstructure:"executor_functions"`
+}
+
+// Validate checks the selectors are 4-byte hex values and the executors addresses
+func (c EncodingConfig) Validate() error {
+	var errs []error
+	for _, selectors := range [][]string{c.Selectors, c.ExecutorEntries, c.ExecutorFunctions} {
+		for _, selector := range selectors {
+			if decoded, err := hexutil.Decode(selector); err != nil || len(decoded) != 4 {
+				errs = append(errs, fmt.Errorf("invalid supported selector %q", selector))
+			}
+		}
+	}
+	for _, executor := range c.Executors {
+		if !common.IsHexAddress(executor) {
+			errs = append(errs, fmt.Errorf("invalid supported executor %q", executor))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RouterEncoding records how the route/build calldata of a result is encoded
+type RouterEncoding struct {
+	Selector          string   `json:"selector"`
+	Executor          string   `json:"executor,omitempty"`
+	ExecutorEntry     string   `json:"executor_entry,omitempty"`
+	ExecutorFunctions []string `json:"executor_functions,omitempty"` // One per swap, in route order
+	Decoded           bool     `json:"decoded"`                      // The executor payload decoded into swaps
+}
+
+// elements lists the encoding as kind and value pairs
+func (e *RouterEncoding) elements() [][2]string {
+	elements := [][2]string{{EncodingSelector, e.Selector}}
+	if e.Executor != "" {
+		elements = append(elements, [2]string{EncodingExecutor, e.Executor})
+	}
+	if e.ExecutorEntry != "" {
+		elements = append(elements, [2]string{EncodingExecutorEntry, e.ExecutorEntry})
+	}
+	for _, function := range e.ExecutorFunctions {
+		elements = append(elements, [2]string{EncodingExecutorFunction, function})
+	}
+	return elements
+}
+
+// decodeRouterEncoding extracts the router selector, executor and executor
+// functions from router calldata. Only the selector is set when the calldata
+// is not a known router swap.
+func decodeRouterEncoding(routerABI abi.ABI, calldata []byte) *RouterEncoding {
+	if len(calldata) < 4 {
+		return nil
+	}
+	encoding := &RouterEncoding{Selector: hexutil.Encode(calldata[:4])}
+
+	call, err := decodeRouterCall(routerABI, calldata)
+	if err != nil {
+		return encoding
+	}
+	encoding.Executor = strings.ToLower(call.executor.Hex())
+
+	if call.method == "swap" && len(call.executorData) >= 4 {
+		encoding.ExecutorEntry = hexutil.Encode(call.executorData[:4])
+	}
+	if payload, err := decodeExecutorPayload(routerABI, call); err == nil {
+		encoding.ExecutorFunctions = payload.functions
+		encoding.Decoded = true
+	}
+	return encoding
+}
+
+// EncodingDrift represents a router encoding element seen for the first time
+// that is not on the supported list
+type EncodingDrift struct {
+	Kind      string    `json:"kind"`
+	Value     string    `json:"value"`
+	ChainName string    `json:"chain_name"`
+	Case      string    `json:"case,omitempty"`
+	ResultID  string    `json:"result_id,omitempty"`
+	FirstSeen time.Time `json:"first_seen"`
+}
+
+// EncodingTracker checks router encodings against the supported list and
+// remembers the unsupported ones already reported
+type EncodingTracker struct {
+	supported map[string]bool // kind:value
+	listed    map[string]bool // Kinds with a supported list
+	stateFile string
+}
+
+// NewEncodingTracker creates a tracker, it returns nil when nothing is configured
+func NewEncodingTracker(config EncodingConfig) *EncodingTracker {
+	supported := make(map[string]bool)
+	listed := make(map[string]bool)
+	for kind, values := range map[string][]string{
+		EncodingSelector:         config.Selectors,
+		EncodingExecutor:         config.Executors,
+		EncodingExecutorEntry:    config.ExecutorEntries,
+		EncodingExecutorFunction: config.ExecutorFunctions,
+	} {
+		for _, value := range values {
+			supported[encodingKey(kind, value)] = true
+			listed[kind] = true
+		}
+	}
+	if len(supported) == 0 && config.StateFile == "" {
+		return nil
+	}
+	return &EncodingTracker{supported: supported, listed: listed, stateFile: config.StateFile}
+}
+
+func encodingKey(kind, value string) string {
+	return kind + ":" + strings.ToLower(value)
+}
+
+// Check returns the unsupported encoding elements of results that were never
+// seen before. The first run with a state file records a baseline without
+// reporting the elements of kinds that have no supported list; elements missing
+// from a supported list are always reported.
+func (t *EncodingTracker) Check(results []*Result, now time.Time) ([]EncodingDrift, error) {
+	seen, baseline, err := t.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []EncodingDrift
+	for _, result := range results {
+		if result.Encoding == nil {
+			continue
+		}
+		for _, element := range result.Encoding.elements() {
+			key := encodingKey(element[0], element[1])
+			if t.supported[key] {
+				continue
+			}
+			if _, exists := seen[key]; exists {
+				continue
+			}
+
+			drift := EncodingDrift{
+				Kind:      element[0],
+				Value:     element[1],
+				ChainName: result.ChainName,
+				Case:      result.Case,
+				ResultID:  result.ID,
+				FirstSeen: now,
+			}
+			seen[key] = drift
+			if !baseline || t.listed[element[0]] {
+				drifts = append(drifts, drift)
+			}
+		}
+	}
+
+	if err := t.save(seen); err != nil {
+		return drifts, err
+	}
+	return drifts, nil
+}
+
+// load reads the encodings seen so far, baseline is set when there is no state yet
+func (t *EncodingTracker) load() (map[string]EncodingDrift, bool, error) {
+	seen := make(map[string]EncodingDrift)
+	if t.stateFile == "" {
+		return seen, false, nil
+	}
+
+	data, err := os.ReadFile(t.stateFile)
+	if os.IsNotExist(err) {
+		return seen, true, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read encoding state: %v", err)
+	}
+
+	var entries []EncodingDrift
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, false, fmt.Errorf("failed to parse encoding state: %v", err)
+		}
+	}
+	for _, entry := range entries {
+		seen[encodingKey(entry.Kind, entry.Value)] = entry
+	}
+	return seen, false, nil
+}
+
+// save writes the encodings seen so far, sorted by kind and value
+func (t *EncodingTracker) save(seen map[string]EncodingDrift) error {
+	if t.stateFile == "" {
+		return nil
+	}
+
+	entries := make([]EncodingDrift, 0, len(seen))
+	for _, entry := range seen {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Kind != entries[j].Kind {
+			return entries[i].Kind < entries[j].Kind
+		}
+		return entries[i].Value < entries[j].Value
+	})
+
+	if err := os.MkdirAll(filepath.Dir(t.stateFile), 0755); err != nil {
+		return fmt.Errorf("failed to create encoding state directory: %v", err)
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal encoding state: %v", err)
+	}
+	if err := os.WriteFile(t.stateFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write encoding state: %v", err)
+	}
+	return nil
+}
@@ -1,14 +1,43 @@
 package monitor
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // CallGetScaledInputDataError represents an error from callGetScaledInputData
 type CallGetScaledInputDataError struct {
 	ChainName string `json:"chain_name"`
 	Message   string `json:"message"`
-	Data      string `json:"data,omitempty"`
+	Data      string `json:"data,omitempty"`   // Hex encoded revert data
+	Reason    string `json:"reason,omitempty"` // Decoded revert reason
 }
 
 func (e *CallGetScaledInputDataError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("Scale Helper Error: %s (reason: %s)", e.Message, e.Reason)
+	}
 	return fmt.Sprintf("Scale Helper Error: %s", e.Message)
 }
+
+// PropertyViolationError represents helper output that executes but breaks a property check
+type PropertyViolationError struct {
+	ChainName  string
+	Violations []PropertyViolation
+}
+
+func newPropertyViolationError(chainName string, violations []PropertyViolation) *PropertyViolationError {
+	return &PropertyViolationError{ChainName: chainName, Violations: violations}
+}
+
+func (e *PropertyViolationError) Error() string {
+	details := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		detail := fmt.Sprintf("%s: %s", violation.Property, violation.Message)
+		if violation.Diff != "" {
+			detail = fmt.Sprintf("%s (%s)", detail, violation.Diff)
+		}
+		details = append(details, detail)
+	}
+	return fmt.Sprintf("Property violated: %s", strings.Join(details, "; "))
+}
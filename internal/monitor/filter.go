@@ -0,0 +1,116 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TestCaseFilter selects a subset of test cases. Empty fields match everything.
+type TestCaseFilter struct {
+	Chains []string // Chain names
+	Tags   []string // Test case tags, any tag matches
+	Cases  []string // Test case keys, see TestCaseKey
+	Pairs  []string // Token pairs as "IN-OUT" using symbols or addresses
+}
+
+// IsEmpty reports whether the filter selects every test case
+func (f TestCaseFilter) IsEmpty() bool {
+	return len(f.Chains) == 0 && len(f.Tags) == 0 && len(f.Cases) == 0 && len(f.Pairs) == 0
+}
+
+// Apply returns the test cases matching the filter
+func (f TestCaseFilter) Apply(testCases []TestCase, tokens map[string]map[string]TokenInfo) []TestCase {
+	if f.IsEmpty() {
+		return testCases
+	}
+
+	var selected []TestCase
+	for _, testCase := range testCases {
+		if f.Matches(testCase, tokens) {
+			selected = append(selected, testCase)
+		}
+	}
+	return selected
+}
+
+// ApplyToChains returns the chains matching the filter
+func (f TestCaseFilter) ApplyToChains(chains []ChainConfig) []ChainConfig {
+	if len(f.Chains) == 0 {
+		return chains
+	}
+
+	var selected []ChainConfig
+	for _, chain := range chains {
+		if containsFold(f.Chains, chain.Name) {
+			selected = append(selected, chain)
+		}
+	}
+	return selected
+}
+
+// Matches reports whether a single test case is selected by the filter
+func (f TestCaseFilter) Matches(testCase TestCase, tokens map[string]map[string]TokenInfo) bool {
+	if len(f.Chains) > 0 && !containsFold(f.Chains, testCase.ChainName) {
+		return false
+	}
+
+	if len(f.Tags) > 0 {
+		matched := false
+		for _, tag := range testCase.Tags {
+			if containsFold(f.Tags, tag) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(f.Cases) > 0 && !containsFold(f.Cases, TestCaseKey(testCase, tokens)) {
+		return false
+	}
+
+	if len(f.Pairs) > 0 {
+		symbolIn := tokens[testCase.ChainName][testCase.TokenIn].Symbol
+		symbolOut := tokens[testCase.ChainName][testCase.TokenOut].Symbol
+		candidates := []string{
+			symbolIn + "-" + symbolOut,
+			testCase.TokenIn + "-" + testCase.TokenOut,
+		}
+		matched := false
+		for _, candidate := range candidates {
+			if containsFold(f.Pairs, candidate) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// TestCaseKey identifies a test case on the command line: its name when set,
+// otherwise "<chain>/<symbol in>-<symbol out>"
+func TestCaseKey(testCase TestCase, tokens map[string]map[string]TokenInfo) string {
+	if testCase.Name != "" {
+		return testCase.Name
+	}
+	return fmt.Sprintf("%s/%s-%s",
+		testCase.ChainName,
+		tokens[testCase.ChainName][testCase.TokenIn].Symbol,
+		tokens[testCase.ChainName][testCase.TokenOut].Symbol,
+	)
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), value) {
+			return true
+		}
+	}
+	return false
+}
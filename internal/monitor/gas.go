@@ -0,0 +1,249 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+)
+
+// Kinds of gas regressions
+const (
+	GasRegressionSwap   = "swap"   // Scaled swap gas diverges from the original swap
+	GasRegressionHelper = "helper" // getScaledInputData got more expensive with new helper code
+)
+
+// GasConfig sets the thresholds of gas regression alerts, 0 disables a check
+type GasConfig struct {
+	MaxSwapDivergencePct float64 `mapstructure:"max_swap_divergence_pct"` // Scaled vs original swap gas, in percent of the original
+	MaxHelperGrowthPct   float64 `mapstructure:"max_helper_growth_pct"`   // Median helper gas of new helper code vs the previous code
+}
+
+// HelperGasSummary aggregates the getScaledInputData gas estimates of a chain's helper in one run
+type HelperGasSummary struct {
+	ChainName string `json:"chain_name"`
+	Helper    string `json:"helper"`
+	CodeHash  string `json:"code_hash,omitempty"` // Code that ran, the implementation's for proxies
+	Median    uint64 `json:"median"`
+	Max       uint64 `json:"max"`
+	Samples   int    `json:"samples"`
+}
+
+// GasRegression represents gas usage beyond a configured threshold
+type GasRegression struct {
+	Kind             string  `json:"kind"`
+	ChainName        string  `json:"chain_name"`
+	Case             string  `json:"case,omitempty"`
+	ResultID         string  `json:"result_id,omitempty"`
+	Helper           string  `json:"helper,omitempty"`
+	PreviousHelper   string  `json:"previous_helper,omitempty"`
+	CodeHash         string  `json:"code_hash,omitempty"`
+	PreviousCodeHash string  `json:"previous_code_hash,omitempty"`
+	Baseline         uint64  `json:"baseline"` // Original swap gas, or the previous helper's median
+	GasUsed          uint64  `json:"gas_used"` // Scaled swap gas, or the new helper's median
+	ChangePct        float64 `json:"change_pct"`
+}
+
+// estimateHelperGas estimates the gas an on-chain caller pays for getScaledInputData
+// at a block, at the latest one for clients that cannot estimate at a given block
+func (m *Monitor) estimateHelperGas(ctx context.Context, client ContractCaller, contractAddress string, inputData []byte, newAmount *big.Int, blockNumber *big.Int) (uint64, error) {
+	data, err := m.contractABI.Pack("getScaledInputData", inputData, newAmount)
+	if err != nil {
+		return 0, fmt.Errorf("failed to pack function call: %v", err)
+	}
+	contractAddr := common.HexToAddress(contractAddress)
+	msg := ethereum.CallMsg{To: &contractAddr, Data: data}
+	var gas uint64
+	if estimator, ok := client.(blockEstimator); ok {
+		gas, err = estimator.EstimateGasAt(ctx, msg, blockNumber)
+	} else {
+		gas, err = client.EstimateGas(ctx, msg)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to estimate helper gas: %v", err)
+	}
+	return gas, nil
+}
+
+// recordHelperGas sets the helper gas of a result, failures are only logged
+func (m *Monitor) recordHelperGas(ctx context.Context, result *Result, client ContractCaller, contractAddress string, inputData []byte, newAmount *big.Int) {
+	gas, err := m.estimateHelperGas(ctx, client, contractAddress, inputData, newAmount, new(big.Int).SetUint64(result.BlockNumber))
+	if err != nil {
+		m.logger.WithError(err).WithField("chain", result.ChainName).Warn("Helper gas not recorded")
+		return
+	}
+	result.HelperGas = gas
+}
+
+// gasChangePct returns how much gas differs from baseline, in percent of baseline
+func gasChangePct(baseline, gas uint64) float64 {
+	return (float64(gas) - float64(baseline)) / float64(baseline) * 100
+}
+
+// summarizeHelperGas computes the median helper gas of each chain, with the
+// code hash of its current helper from the deployment checks of the run
+func (m *Monitor) summarizeHelperGas(results []*Result, deployments []DeploymentCheck) []HelperGasSummary {
+	codeHashes := make(map[string]string)
+	for _, check := range deployments {
+		if check.Label == CurrentHelper {
+			codeHashes[check.ChainName] = check.effectiveHash()
+		}
+	}
+
+	samples := make(map[string][]uint64)
+	var order []string
+	for _, result := range results {
+		if result.HelperGas == 0 {
+			continue
+		}
+		if _, exists := samples[result.ChainName]; !exists {
+			order = append(order, result.ChainName)
+		}
+		samples[result.ChainName] = append(samples[result.ChainName], result.HelperGas)
+	}
+	sort.Strings(order)
+
+	summaries := make([]HelperGasSummary, 0, len(order))
+	for _, chainName := range order {
+		chainConfig := m.findChain(chainName)
+		if chainConfig == nil {
+			continue
+		}
+		gas := samples[chainName]
+		sort.Slice(gas, func(i, j int) bool { return gas[i] < gas[j] })
+		summaries = append(summaries, HelperGasSummary{
+			ChainName: chainName,
+			Helper:    chainConfig.ContractAddress,
+			CodeHash:  codeHashes[chainName],
+			Median:    gas[len(gas)/2],
+			Max:       gas[len(gas)-1],
+			Samples:   len(gas),
+		})
+	}
+	return summaries
+}
+
+// checkGas records the helper gas of a run and returns the regressions: scaled
+// swaps whose gas diverges from the original swap, and helpers whose median gas
+// grew compared with the previous helper code on the same chain
+func (m *Monitor) checkGas(run *RunRecord) []GasRegression {
+	run.HelperGas = m.summarizeHelperGas(run.Results, run.Deployments)
+	cfg := m.config.Gas
+
+	var regressions []GasRegression
+	if cfg.MaxSwapDivergencePct > 0 {
+		for _, result := range run.Results {
+			if !result.IsSuccess || result.OriginalGasUsed == 0 || result.ScaledGasUsed == 0 {
+				continue
+			}
+			change := gasChangePct(result.OriginalGasUsed, result.ScaledGasUsed)
+			if math.Abs(change) > cfg.MaxSwapDivergencePct {
+				regressions = append(regressions, GasRegression{
+					Kind:      GasRegressionSwap,
+					ChainName: result.ChainName,
+					Case:      result.Case,
+					ResultID:  result.ID,
+					Baseline:  result.OriginalGasUsed,
+					GasUsed:   result.ScaledGasUsed,
+					ChangePct: change,
+				})
+			}
+		}
+	}
+
+	if cfg.MaxHelperGrowthPct > 0 && m.history != nil && len(run.HelperGas) > 0 {
+		runs, err := m.history.LoadRuns()
+		if err != nil {
+			m.logger.WithError(err).Warn("Failed to load history for helper gas")
+		}
+		for _, summary := range run.HelperGas {
+			previous := previousHelperGas(runs, summary.ChainName)
+			if previous == nil || !helperChanged(previous, &summary) || previous.Median == 0 {
+				continue
+			}
+			change := gasChangePct(previous.Median, summary.Median)
+			if change > cfg.MaxHelperGrowthPct {
+				regressions = append(regressions, GasRegression{
+					Kind:             GasRegressionHelper,
+					ChainName:        summary.ChainName,
+					Helper:           summary.Helper,
+					PreviousHelper:   previous.Helper,
+					CodeHash:         summary.CodeHash,
+					PreviousCodeHash: previous.CodeHash,
+					Baseline:         previous.Median,
+					GasUsed:          summary.Median,
+					ChangePct:        change,
+				})
+			}
+		}
+	}
+
+	m.sendGasNotice(regressions)
+	return regressions
+}
+
+// helperChanged tells whether two summaries measured different helper code. The
+// code hashes are compared, so an upgrade behind the same proxy address counts,
+// and the addresses when a summary has no hash.
+func helperChanged(previous, current *HelperGasSummary) bool {
+	if previous.CodeHash != "" && current.CodeHash != "" {
+		return !strings.EqualFold(previous.CodeHash, current.CodeHash)
+	}
+	return !strings.EqualFold(previous.Helper, current.Helper)
+}
+
+// previousHelperGas returns the latest helper gas summary of a chain in the history
+func previousHelperGas(runs []*RunRecord, chainName string) *HelperGasSummary {
+	for i := len(runs) - 1; i >= 0; i-- {
+		for j := range runs[i].HelperGas {
+			if runs[i].HelperGas[j].ChainName == chainName {
+				return &runs[i].HelperGas[j]
+			}
+		}
+	}
+	return nil
+}
+
+// sendGasNotice logs the gas regressions and posts them to Slack, unless running in dry-run mode
+func (m *Monitor) sendGasNotice(regressions []GasRegression) {
+	if len(regressions) == 0 {
+		return
+	}
+
+	lines := make([]string, 0, len(regressions))
+	for _, regression := range regressions {
+		m.logger.WithFields(logrus.Fields{
+			"kind":     regression.Kind,
+			"chain":    regression.ChainName,
+			"baseline": regression.Baseline,
+			"gas":      regression.GasUsed,
+			"change":   fmt.Sprintf("%+.1f%%", regression.ChangePct),
+		}).Warn("Gas regression")
+		lines = append(lines, regression.String())
+	}
+
+	if m.config.DryRun {
+		m.logger.WithField("regressions", len(regressions)).Info("Dry run, skipping Slack gas alert")
+		return
+	}
+	title := fmt.Sprintf("⛽ Scale Helper Monitor - %d gas regressions", len(regressions))
+	if err := m.slackClient.SendNotice(title, lines); err != nil {
+		m.logger.WithError(err).Error("Failed to send Slack gas alert")
+	}
+}
+
+// String describes the regression in one line
+func (r GasRegression) String() string {
+	if r.Kind == GasRegressionHelper {
+		return fmt.Sprintf("helper on %s: median %d gas at `%s` (code %s), %d at `%s` (code %s) (%+.1f%%)",
+			r.ChainName, r.GasUsed, r.Helper, orNone(r.CodeHash), r.Baseline, r.PreviousHelper, orNone(r.PreviousCodeHash), r.ChangePct)
+	}
+	return fmt.Sprintf("swap on %s: scaled %d gas vs original %d (%+.1f%%), case %s, result `%s`",
+		r.ChainName, r.GasUsed, r.Baseline, r.ChangePct, r.Case, r.ResultID)
+}
@@ -0,0 +1,305 @@
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// defaultMaxHistoryRuns is used when monitoring.max_history_runs is not configured
+const defaultMaxHistoryRuns = 100
+
+// errHistoryCorrupt is returned by LoadRuns when the history file does not parse
+var errHistoryCorrupt = errors.New("history file is corrupt")
+
+// RunRecord represents a single monitoring run kept in the history file
+type RunRecord struct {
+	ID               string              `json:"id"`
+	StartedAt        time.Time           `json:"started_at"`
+	FinishedAt       time.Time           `json:"finished_at"`
+	Total            int                 `json:"total"`
+	Failures         int                 `json:"failures"`
+	Errors           int                 `json:"errors"`
+	SizeBuckets      []SizeBucketSummary `json:"size_buckets"`
+	EnvironmentDiffs []EnvironmentDiff   `json:"environment_diffs,omitempty"`
+	NewEncodings     []EncodingDrift     `json:"new_encodings,omitempty"`
+	HelperGas        []HelperGasSummary  `json:"helper_gas,omitempty"`
+	GasRegressions   []GasRegression     `json:"gas_regressions,omitempty"`
+	Deployments      []DeploymentCheck   `json:"deployments,omitempty"`
+	RPCHealth        []ChainRPCHealth    `json:"rpc_health,omitempty"`
+	Simulation       *SimulationUsage    `json:"simulation,omitempty"`
+	Results          []*Result           `json:"results"`
+}
+
+// SizeBucketSummary aggregates results of a chain for one swap size bucket
+type SizeBucketSummary struct {
+	ChainName  string `json:"chain_name"`
+	SizeBucket string `json:"size_bucket"`
+	Total      int    `json:"total"`
+	Failures   int    `json:"failures"`
+	Errors     int    `json:"errors"`
+}
+
+// HistoryStore persists monitoring runs to a JSON file
+type HistoryStore struct {
+	historyFile string
+	maxRuns     int
+}
+
+// NewHistoryStore creates a new history store
+func NewHistoryStore(historyFile string, maxRuns int) *HistoryStore {
+	if maxRuns <= 0 {
+		maxRuns = defaultMaxHistoryRuns
+	}
+	return &HistoryStore{
+		historyFile: historyFile,
+		maxRuns:     maxRuns,
+	}
+}
+
+// SaveRun appends a run to the history file, dropping the oldest runs beyond the limit.
+// The history is left untouched when it cannot be read. A history that does not
+// parse is moved aside and a new one is started, the returned error names it.
+func (hs *HistoryStore) SaveRun(run *RunRecord) error {
+	runs, err := hs.LoadRuns()
+	var movedAside error
+	if errors.Is(err, errHistoryCorrupt) {
+		aside := fmt.Sprintf("%s.corrupt-%s", hs.historyFile, time.Now().UTC().Format("20060102T150405Z"))
+		if renameErr := os.Rename(hs.historyFile, aside); renameErr != nil {
+			return fmt.Errorf("%v, and moving it aside failed: %v", err, renameErr)
+		}
+		movedAside = fmt.Errorf("%v, moved to %s and started a new history", err, aside)
+		runs = nil
+	} else if err != nil {
+		return err
+	}
+
+	runs = append(runs, run)
+	if len(runs) > hs.maxRuns {
+		runs = runs[len(runs)-hs.maxRuns:]
+	}
+
+	if err := hs.saveRunsToFile(runs); err != nil {
+		return err
+	}
+	return movedAside
+}
+
+// LoadRuns loads all runs from the history file, oldest first
+func (hs *HistoryStore) LoadRuns() ([]*RunRecord, error) {
+	if _, err := os.Stat(hs.historyFile); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(hs.historyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history file: %v", err)
+	}
+
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var runs []*RunRecord
+	if err := json.Unmarshal(data, &runs); err != nil {
+		return nil, fmt.Errorf("%w: %v", errHistoryCorrupt, err)
+	}
+
+	return runs, nil
+}
+
+// FindRun looks up a run by ID, an empty ID returns the latest run
+func (hs *HistoryStore) FindRun(id string) (*RunRecord, error) {
+	runs, err := hs.LoadRuns()
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("history has no runs")
+	}
+	if id == "" {
+		return runs[len(runs)-1], nil
+	}
+
+	for _, run := range runs {
+		if run.ID == id {
+			return run, nil
+		}
+	}
+	return nil, fmt.Errorf("no run found with ID %s", id)
+}
+
+// FindResults looks up stored results by ID. A result ID returns that result,
+// a run ID returns the scale failures of that run.
+func (hs *HistoryStore) FindResults(id string) ([]*Result, error) {
+	runs, err := hs.LoadRuns()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, run := range runs {
+		if run.ID == id {
+			var failures []*Result
+			for _, result := range run.Results {
+				if result.FailureType == FailureTypeScale {
+					failures = append(failures, result)
+				}
+			}
+			if len(failures) == 0 {
+				return nil, fmt.Errorf("run %s has no scale failures to replay", id)
+			}
+			return failures, nil
+		}
+
+		for _, result := range run.Results {
+			if result.ID == id {
+				return []*Result{result}, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no run or result found with ID %s", id)
+}
+
+// saveRunsToFile saves runs to the JSON file
+func (hs *HistoryStore) saveRunsToFile(runs []*RunRecord) error {
+	dir := filepath.Dir(hs.historyFile)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(runs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal history: %v", err)
+	}
+
+	// Write then rename, so an interrupted write cannot truncate the history
+	tmp := hs.historyFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write history file: %v", err)
+	}
+	if err := os.Rename(tmp, hs.historyFile); err != nil {
+		return fmt.Errorf("failed to replace history file: %v", err)
+	}
+
+	return nil
+}
+
+// Outcomes of a result in an EnvironmentDiff
+const (
+	OutcomePass  = "pass"
+	OutcomeFail  = "fail"
+	OutcomeError = "error"
+)
+
+// EnvironmentDiff represents a test case whose outcome depends on the aggregator environment
+type EnvironmentDiff struct {
+	Case       string            `json:"case"`
+	ChainName  string            `json:"chain_name"`
+	SizeBucket string            `json:"size_bucket"`
+	Scenario   string            `json:"scenario,omitempty"`
+	Outcomes   map[string]string `json:"outcomes"` // environment -> OutcomePass, OutcomeFail or OutcomeError
+	ResultIDs  map[string]string `json:"result_ids"`
+}
+
+// summarizeEnvironmentDiffs compares the results of each test case and size
+// across aggregator environments, keeping those that pass in one environment
+// and fail in another
+func summarizeEnvironmentDiffs(results []*Result) []EnvironmentDiff {
+	type caseKey struct {
+		caseName   string
+		sizeBucket string
+		scenario   string
+	}
+
+	diffs := make(map[caseKey]*EnvironmentDiff)
+	var order []caseKey
+	for _, result := range results {
+		if result.Environment == "" {
+			continue
+		}
+
+		key := caseKey{caseName: result.Case, sizeBucket: result.SizeBucket, scenario: result.Scenario}
+		diff, exists := diffs[key]
+		if !exists {
+			diff = &EnvironmentDiff{
+				Case:       result.Case,
+				ChainName:  result.ChainName,
+				SizeBucket: result.SizeBucket,
+				Scenario:   result.Scenario,
+				Outcomes:   make(map[string]string),
+				ResultIDs:  make(map[string]string),
+			}
+			diffs[key] = diff
+			order = append(order, key)
+		}
+
+		outcome := OutcomePass
+		switch result.FailureType {
+		case FailureTypeScale, FailureTypeProperty:
+			outcome = OutcomeFail
+		case FailureTypeInfra:
+			outcome = OutcomeError
+		}
+		diff.Outcomes[result.Environment] = outcome
+		diff.ResultIDs[result.Environment] = result.ID
+	}
+
+	var summaries []EnvironmentDiff
+	for _, key := range order {
+		diff := diffs[key]
+		seen := make(map[string]bool)
+		for _, outcome := range diff.Outcomes {
+			seen[outcome] = true
+		}
+		if seen[OutcomePass] && seen[OutcomeFail] {
+			summaries = append(summaries, *diff)
+		}
+	}
+	return summaries
+}
+
+// summarizeSizeBuckets groups the test case results by chain and size bucket
+func summarizeSizeBuckets(results []*Result) []SizeBucketSummary {
+	type bucketKey struct {
+		chainName  string
+		sizeBucket string
+	}
+
+	buckets := make(map[bucketKey]*SizeBucketSummary)
+	var order []bucketKey
+	for _, result := range results {
+		// Edge scenarios probe amounts outside the bucket
+		if result.Scenario != "" {
+			continue
+		}
+		key := bucketKey{chainName: result.ChainName, sizeBucket: result.SizeBucket}
+		bucket, exists := buckets[key]
+		if !exists {
+			bucket = &SizeBucketSummary{ChainName: result.ChainName, SizeBucket: result.SizeBucket}
+			buckets[key] = bucket
+			order = append(order, key)
+		}
+		bucket.Total++
+		switch result.FailureType {
+		case FailureTypeScale, FailureTypeProperty:
+			bucket.Failures++
+		case FailureTypeInfra:
+			bucket.Errors++
+		}
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return order[i].chainName < order[j].chainName
+	})
+
+	summaries := make([]SizeBucketSummary, 0, len(order))
+	for _, key := range order {
+		summaries = append(summaries, *buckets[key])
+	}
+	return summaries
+}
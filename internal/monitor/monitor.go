@@ -2,9 +2,9 @@ package monitor
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"math"
 	"math/big"
 	"math/rand"
 	"strconv"
@@ -15,12 +15,13 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/sirupsen/logrus"
 
 	"scale-helper-monitor/internal/clients/kyberswap"
+	"scale-helper-monitor/internal/clients/rpcpool"
 	"scale-helper-monitor/internal/clients/slack"
 	"scale-helper-monitor/internal/clients/tenderly"
+	"scale-helper-monitor/internal/revert"
 )
 
 // Monitor represents the main monitoring service
@@ -34,8 +35,17 @@ type Monitor struct {
 	kyberClient       *kyberswap.Client
 	slackClient       *slack.Client
 	tenderlyClient    *tenderly.Client
-	ethClients        map[string]*ethclient.Client
+	ethClients        map[string]ContractCaller
 	contractABI       abi.ABI
+	routerABI         abi.ABI
+	revertDecoder     *revert.Decoder
+	fallbackSimulator Simulator         // nil when no fallback is configured
+	encodings         *EncodingTracker  // nil when no supported encodings or state file are configured
+	deployments       []DeploymentCheck // Last helper deployment checks, nil before the first
+	rpcDown           map[string]bool   // Chains whose RPC endpoints were all unhealthy at the last check
+	properties        []PropertyCheck
+	simulation        *SimulationUsage // Simulator usage of the current run
+	history           *HistoryStore
 	logger            *logrus.Logger
 }
 
@@ -53,24 +63,19 @@ func NewMonitor(
 	logger *logrus.Logger,
 ) (*Monitor, error) {
 
-	// Create Ethereum clients for each chain
-	ethClients := make(map[string]*ethclient.Client)
+	maxHeadAge, err := config.RPC.HeadAge()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create an RPC pool for each chain, endpoints are dialed on first use
+	ethClients := make(map[string]ContractCaller)
 	for _, chain := range chains {
-		if chain.RPCURL == "" {
+		if len(chain.RPCURLs) == 0 {
 			logger.WithField("chain", chain.Name).Warn("RPC URL not configured for chain, skipping")
 			continue
 		}
-
-		client, err := ethclient.Dial(chain.RPCURL)
-		if err != nil {
-			logger.WithFields(logrus.Fields{
-				"chain": chain.Name,
-				"error": err,
-			}).Error("Failed to connect to RPC endpoint")
-			continue
-		}
-
-		ethClients[chain.Name] = client
+		ethClients[chain.Name] = rpcpool.New(chain.Name, int64(chain.ChainID), chain.RPCURLs, maxHeadAge, logger)
 	}
 
 	// Create contract ABI
@@ -79,6 +84,38 @@ func NewMonitor(
 		return nil, fmt.Errorf("failed to create contract ABI: %w", err)
 	}
 
+	routerABI, err := createRouterABI()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create router ABI: %w", err)
+	}
+
+	// Decode reverts with the built-in error registry plus the configured ABIs
+	revertDecoder, err := revert.NewDecoder(config.ErrorABIFiles...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create revert decoder: %w", err)
+	}
+
+	properties, err := newPropertyChecks(config.PropertyChecks)
+	if err != nil {
+		return nil, err
+	}
+
+	fallbackSimulator, err := newFallbackSimulator(config.FallbackSimulator, chains, ethClients)
+	if err != nil {
+		return nil, err
+	}
+
+	// Expand test cases into one sub-case per configured swap size
+	testCases, err = expandTestCases(testCases, tokens)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand test cases: %w", err)
+	}
+
+	var history *HistoryStore
+	if config.HistoryFile != "" {
+		history = NewHistoryStore(config.HistoryFile, config.MaxHistoryRuns)
+	}
+
 	return &Monitor{
 		config:            config,
 		chains:            chains,
@@ -88,6 +125,14 @@ func NewMonitor(
 		tenderlyClient:    tenderlyClient,
 		ethClients:        ethClients,
 		contractABI:       contractABI,
+		routerABI:         routerABI,
+		revertDecoder:     revertDecoder,
+		fallbackSimulator: fallbackSimulator,
+		encodings:         NewEncodingTracker(config.Encodings),
+		rpcDown:           make(map[string]bool),
+		properties:        properties,
+		simulation:        &SimulationUsage{Backend: tenderlyBackend},
+		history:           history,
 		logger:            logger,
 		tokens:            tokens,
 		testCases:         testCases,
@@ -132,68 +177,109 @@ func createContractABI() (abi.ABI, error) {
 
 // MonitorChain monitors a specific chain with a test token pair
 func (m *Monitor) MonitorChain(ctx context.Context, testCase TestCase) (*Result, error) {
-	amount, err := strconv.ParseFloat(testCase.Amount, 64)
-
-	if err != nil {
-		return nil, fmt.Errorf("invalid amount format: %s", testCase.Amount)
+	result, err := m.monitorChain(ctx, testCase)
+	if result != nil {
+		finalizeVersionDiffs(result, err, m.logger)
 	}
+	return result, err
+}
 
-	decimals, ok := new(big.Int).SetString(m.tokens[testCase.ChainName][testCase.TokenIn].Decimals, 10)
-	if !ok {
-		return nil, fmt.Errorf("invalid decimals format: %s", m.tokens[testCase.ChainName][testCase.TokenIn].Decimals)
+func (m *Monitor) monitorChain(ctx context.Context, testCase TestCase) (*Result, error) {
+	tokenIn := m.tokens[testCase.ChainName][testCase.TokenIn]
+
+	// Every result of this check shares the same identifying fields
+	base := Result{
+		Case:        TestCaseKey(testCase, m.tokens),
+		ChainName:   testCase.ChainName,
+		TokenIn:     testCase.TokenIn,
+		TokenOut:    testCase.TokenOut,
+		Amount:      testCase.Amount,
+		Sender:      testCase.sender(),
+		SizeBucket:  testCase.SizeBucket,
+		Environment: testCase.Environment,
+	}
+	fail := func(err error, errorMsg string) (*Result, error) {
+		result := base
+		result.Error = errorMsg
+		return &result, err
 	}
 
-	// Check if amount is a integer
-	if amount == float64(int64(amount)) {
-		// For whole numbers, create string with zeros appended
-		zeros := strings.Repeat("0", int(decimals.Int64()))
-		testCase.Amount = testCase.Amount + zeros
-	} else {
-		amountInt := int64(amount * math.Pow10(int(decimals.Int64())))
-		testCase.Amount = big.NewInt(amountInt).String()
+	decimals, err := strconv.ParseInt(tokenIn.Decimals, 10, 64)
+	if err != nil {
+		err = fmt.Errorf("invalid decimals format: %s", tokenIn.Decimals)
+		return fail(err, err.Error())
 	}
 
-	// Find the chain config
-	var chainConfig *ChainConfig
-	for _, chain := range m.chains {
-		if chain.Name == testCase.ChainName {
-			chainConfig = &chain
-			break
-		}
+	amountIn, err := toBaseUnits(testCase.Amount, decimals)
+	if err != nil {
+		return fail(err, err.Error())
 	}
+	testCase.Amount = amountIn.String()
+	base.Amount = testCase.Amount
 
+	// Find the chain config
+	chainConfig := m.findChain(testCase.ChainName)
 	if chainConfig == nil {
-		return nil, fmt.Errorf("chain %s not found in configuration", testCase.ChainName)
+		err := fmt.Errorf("chain %s not found in configuration", testCase.ChainName)
+		return fail(err, err.Error())
 	}
 
 	// Get Ethereum client
 	ethClient, exists := m.ethClients[chainConfig.Name]
 	if !exists {
-		return nil, fmt.Errorf("ethereum client not available for chain %s", chainConfig.Name)
+		err := fmt.Errorf("ethereum client not available for chain %s", chainConfig.Name)
+		return fail(err, err.Error())
 	}
+	if m.helperMissing(chainConfig.Name) {
+		err := fmt.Errorf("no scale helper code at %s on chain %s", chainConfig.ContractAddress, chainConfig.Name)
+		return fail(err, err.Error())
+	}
+
+	// Pin the helper call and both simulations to the same block so the original
+	// and scaled swaps always run against identical pool state. Sibling sub-cases
+	// share the block of the first one. The chain calls stay on the endpoint that
+	// served the block.
+	var pinned *big.Int
+	if testCase.siblings != nil && testCase.siblings.block != 0 {
+		pinned = new(big.Int).SetUint64(testCase.siblings.block)
+	}
+	header, ethClient, err := pinBlock(ctx, ethClient, pinned)
+	if err != nil {
+		return fail(err, fmt.Sprintf("Failed to get latest block: %v", err))
+	}
+	blockNumber := header.Number.Uint64()
+	if testCase.siblings != nil {
+		testCase.siblings.block = blockNumber
+	}
+	base.BlockNumber = blockNumber
+	base.RPCEndpoint = servedBy(ethClient)
 
-	// Fetch route from KyberSwap
-	routeEncodedData, route, err := m.kyberClient.GetRoute(
+	// Fetch route from KyberSwap, from the test case's aggregator environment
+	kyberClient, err := m.kyberClient.Environment(testCase.Environment)
+	if err != nil {
+		return fail(err, err.Error())
+	}
+	routeEncodedData, route, err := kyberClient.GetRoute(
 		chainConfig.Name,
 		testCase.TokenIn,
 		testCase.TokenOut,
 		testCase.Amount,
 		m.liquiditySources[chainConfig.Name],
 		testCase.IncludedSources,
+		testCase.routeParams(),
 	)
 
 	if err != nil {
-		return &Result{
-			ChainName: testCase.ChainName,
-			TokenIn:   m.tokens[testCase.ChainName][testCase.TokenIn].Symbol,
-			TokenOut:  m.tokens[testCase.ChainName][testCase.TokenOut].Symbol,
-			Amount:    testCase.Amount,
-			Error:     fmt.Sprintf("Failed to fetch route: %v", err),
-		}, err
+		return fail(err, fmt.Sprintf("Failed to fetch route: %v", err))
 	}
 
+	base.InputData = routeEncodedData.Data
+	base.RouterAddress = routeEncodedData.RouterAddress
+	base.TransactionValue = routeEncodedData.TransactionValue
+	base.Route = route.Route
+
 	// Step 1: Simulate original swap with Tenderly
-	fromAddress := "0xdeAD00000000000000000000000000000000dEAd"
+	fromAddress := base.Sender
 
 	// Create state objects for fake balances and
 	stateObjects, err := m.tenderlyClient.CreateStateObjectsForSwap(
@@ -202,21 +288,14 @@ func (m *Monitor) MonitorChain(ctx context.Context, testCase TestCase) (*Result,
 		fromAddress,
 		routeEncodedData.AmountIn,
 		chainConfig.Name,
-		m.tokens[chainConfig.Name][testCase.TokenIn].Slot,
+		tokenIn.Storage(),
 	)
 	if err != nil {
-		return &Result{
-			ChainName: chainConfig.Name,
-			TokenIn:   m.tokens[testCase.ChainName][testCase.TokenIn].Symbol,
-			TokenOut:  m.tokens[testCase.ChainName][testCase.TokenOut].Symbol,
-			Amount:    testCase.Amount,
-			Error:     fmt.Sprintf("Failed to create state objects: %v", err),
-		}, err
+		return fail(err, fmt.Sprintf("Failed to create state objects: %v", err))
 	}
 
-	// Simulate original swap
-	originalSuccess, originalError, originalTenderlyURL, err := m.tenderlyClient.SimulateTransaction(
-		ctx,
+	// Simulate original swap, keeping the payload so it can be attached to alerts
+	originalBundle := m.tenderlyClient.NewSwapBundle(
 		tenderly.GetChainNetworkID(chainConfig.ChainID),
 		testCase.TokenIn,
 		fromAddress,
@@ -224,111 +303,104 @@ func (m *Monitor) MonitorChain(ctx context.Context, testCase TestCase) (*Result,
 		routeEncodedData.Data,
 		routeEncodedData.TransactionValue,
 		stateObjects,
+		tenderly.NeedsApproval(testCase.TokenIn, tokenIn.Storage()),
+		blockNumber,
 	)
+	base.OriginalSimulationPayload = marshalPayload(originalBundle)
+	original, simulator, err := m.simulate(ctx, originalBundle)
+	base.Simulator = simulator
 	if err != nil {
-		return &Result{
-			ChainName: chainConfig.Name,
-			TokenIn:   m.tokens[testCase.ChainName][testCase.TokenIn].Symbol,
-			TokenOut:  m.tokens[testCase.ChainName][testCase.TokenOut].Symbol,
-			Amount:    testCase.Amount,
-			Error:     fmt.Sprintf("Original Tenderly simulation failed: %v", err),
-		}, err
+		return fail(err, fmt.Sprintf("Original %s simulation failed: %v", simulator, err))
 	}
 
+	base.OriginalTenderlyURL = original.URL
+	base.OriginalGasUsed = original.GasUsed
+
 	// Check if original simulation succeeded
-	if !originalSuccess {
+	if !original.Success {
 		errorMsg := "Original swap simulation failed"
-		if originalError != "" {
-			errorMsg = fmt.Sprintf("Original swap failed: %s", originalError, "Tenderly URL: ", originalTenderlyURL)
+		if original.ErrorMessage != "" {
+			errorMsg = fmt.Sprintf("Original swap failed: %s", original.ErrorMessage)
 		}
+		m.recordSimulationFailure(&base, original)
 
-		return &Result{
-			ChainName:           chainConfig.Name,
-			TokenIn:             testCase.TokenIn,
-			TokenOut:            testCase.TokenOut,
-			Amount:              testCase.Amount,
-			Error:               errorMsg,
-			OriginalTenderlyURL: originalTenderlyURL,
-		}, fmt.Errorf(errorMsg)
+		return fail(errors.New(errorMsg), errorMsg)
 	}
 
 	// Step 2: Call scale helper to get modified data
 	inputData, err := hexutil.Decode(routeEncodedData.Data)
 	if err != nil {
-		return &Result{
-			ChainName:           chainConfig.Name,
-			TokenIn:             testCase.TokenIn,
-			TokenOut:            testCase.TokenOut,
-			Amount:              testCase.Amount,
-			Error:               fmt.Sprintf("Failed to decode input data: %v", err),
-			OriginalTenderlyURL: originalTenderlyURL,
-		}, err
+		return fail(err, fmt.Sprintf("Failed to decode input data: %v", err))
 	}
+	base.Encoding = decodeRouterEncoding(m.routerABI, inputData)
 
 	originalAmount, ok := new(big.Int).SetString(routeEncodedData.AmountIn, 10)
 
 	if !ok {
-		return &Result{
-			ChainName:           chainConfig.Name,
-			TokenIn:             testCase.TokenIn,
-			TokenOut:            testCase.TokenOut,
-			Amount:              testCase.Amount,
-			Error:               "Failed to parse input amount",
-			OriginalTenderlyURL: originalTenderlyURL,
-		}, fmt.Errorf("failed to parse input amount")
-	}
-
-	newAmount := originalAmount
-	scaleDown := rand.Intn(1000000)%2 == 0
-	if !m.allowScalingUp(route.Route, m.onlyScaleDownDexs) {
-		scaleDown = true
-	}
-	percentage := rand.Intn(20)
-	if scaleDown {
-		newAmount = new(big.Int).Mul(originalAmount, big.NewInt(int64(100-percentage)))
-		newAmount = new(big.Int).Div(newAmount, big.NewInt(100))
-	} else {
-		newAmount = new(big.Int).Mul(originalAmount, big.NewInt(int64(100+percentage)))
-		newAmount = new(big.Int).Div(newAmount, big.NewInt(100))
+		return fail(fmt.Errorf("failed to parse input amount"), "Failed to parse input amount")
 	}
 
+	scale := pickScale()
+	if testCase.siblings != nil {
+		scale = testCase.siblings.scale
+	}
+	newAmount := scale.apply(originalAmount, !m.allowScalingUp(route.Route, m.onlyScaleDownDexs))
+	base.NewAmount = newAmount.String()
+
 	// Call the scale helper contract
-	scaleResult, err := m.callGetScaledInputData(ctx, ethClient, chainConfig.ContractAddress, inputData, newAmount)
+	scaleResult, err := m.callGetScaledInputData(ctx, ethClient, chainConfig.ContractAddress, inputData, newAmount, new(big.Int).SetUint64(blockNumber))
+
+	// Give other helper deployments the same input, before any early return
+	swap := scaledSwap{
+		chain:   chainConfig,
+		tokenIn: testCase.TokenIn,
+		token:   tokenIn,
+		router:  routeEncodedData.RouterAddress,
+		from:    fromAddress,
+		value:   routeEncodedData.TransactionValue,
+		amount:  newAmount.String(),
+		block:   blockNumber,
+	}
+	if scaleResult != nil {
+		swap.currentOK, swap.current = scaleResult.IsSuccess, scaleResult.Data
+	}
+	errors.As(err, &swap.currentRevert)
+	base.VersionDiffs = m.compareHelpers(ctx, ethClient, swap, inputData, newAmount)
+
 	if err != nil {
-		return &Result{
-			ChainName:           chainConfig.Name,
-			TokenIn:             m.tokens[testCase.ChainName][testCase.TokenIn].Symbol,
-			TokenOut:            m.tokens[testCase.ChainName][testCase.TokenOut].Symbol,
-			Amount:              testCase.Amount,
-			InputData:           routeEncodedData.Data,
-			NewAmount:           newAmount.String(),
-			Error:               fmt.Sprintf("Scale Failed: %v", err),
-			Route:               route.Route,
-			OriginalTenderlyURL: originalTenderlyURL,
-		}, err
+		var helperErr *CallGetScaledInputDataError
+		if errors.As(err, &helperErr) && helperErr.Reason != "" {
+			base.RevertReason = helperErr.Reason
+			base.RevertContract = chainConfig.ContractAddress
+		}
+		return fail(err, fmt.Sprintf("Scale Failed: %v", err))
 	}
 
+	base.ReturnedData = hexutil.Encode(scaleResult.Data)
+	base.RPCEndpoint = scaleResult.Endpoint
+	m.recordHelperGas(ctx, &base, ethClient, chainConfig.ContractAddress, inputData, newAmount)
+
 	if !scaleResult.IsSuccess {
 		scaleErr := &CallGetScaledInputDataError{
 			ChainName: chainConfig.Name,
 			Message:   "Scale helper returned false",
 		}
-		return &Result{
-			ChainName:           chainConfig.Name,
-			TokenIn:             testCase.TokenIn,
-			TokenOut:            testCase.TokenOut,
-			Amount:              testCase.Amount,
-			IsSuccess:           scaleResult.IsSuccess,
-			ReturnedData:        hexutil.Encode(scaleResult.Data),
-			InputData:           routeEncodedData.Data,
-			NewAmount:           newAmount.String(),
-			Route:               route.Route,
-			Error:               "Scale helper returned false",
-			OriginalTenderlyURL: originalTenderlyURL,
-		}, scaleErr
-	}
-
-	// Step 3: Simulate scaled swap with Tenderly
+		return fail(scaleErr, "Scale helper returned false")
+	}
+
+	// Step 3: Check the properties of the helper output
+	base.PropertyViolations = m.checkProperties(ctx, PropertyInput{
+		InputData:      inputData,
+		OriginalAmount: originalAmount,
+		NewAmount:      newAmount,
+		Output:         scaleResult.Data,
+		RouterABI:      m.routerABI,
+		Scale: func(ctx context.Context, data []byte, amount *big.Int) (*ContractCallResult, error) {
+			return m.callGetScaledInputData(ctx, ethClient, chainConfig.ContractAddress, data, amount, new(big.Int).SetUint64(blockNumber))
+		},
+	})
+
+	// Step 4: Simulate scaled swap with Tenderly
 	scaledData := hexutil.Encode(scaleResult.Data)
 
 	// Create state objects for scaled amount
@@ -338,21 +410,14 @@ func (m *Monitor) MonitorChain(ctx context.Context, testCase TestCase) (*Result,
 		fromAddress,
 		newAmount.String(),
 		chainConfig.Name,
-		m.tokens[chainConfig.Name][testCase.TokenIn].Slot,
+		tokenIn.Storage(),
 	)
 	if err != nil {
-		return &Result{
-			ChainName: chainConfig.Name,
-			TokenIn:   m.tokens[testCase.ChainName][testCase.TokenIn].Symbol,
-			TokenOut:  m.tokens[testCase.ChainName][testCase.TokenOut].Symbol,
-			Amount:    testCase.Amount,
-			Error:     fmt.Sprintf("Failed to create scaled state objects: %v", err),
-		}, err
+		return fail(err, fmt.Sprintf("Failed to create scaled state objects: %v", err))
 	}
 
 	// Simulate scaled swap
-	scaledSuccess, scaledError, scaledTenderlyURL, err := m.tenderlyClient.SimulateTransaction(
-		ctx,
+	scaledBundle := m.tenderlyClient.NewSwapBundle(
 		tenderly.GetChainNetworkID(chainConfig.ChainID),
 		testCase.TokenIn,
 		fromAddress,
@@ -360,68 +425,76 @@ func (m *Monitor) MonitorChain(ctx context.Context, testCase TestCase) (*Result,
 		scaledData,
 		routeEncodedData.TransactionValue,
 		scaledStateObjects,
+		tenderly.NeedsApproval(testCase.TokenIn, tokenIn.Storage()),
+		blockNumber,
 	)
+	base.ScaledSimulationPayload = marshalPayload(scaledBundle)
+	scaled, simulator, err := m.simulate(ctx, scaledBundle)
+	base.Simulator = simulator
 	if err != nil {
-		return &Result{
-			ChainName:           chainConfig.Name,
-			TokenIn:             testCase.TokenIn,
-			TokenOut:            testCase.TokenOut,
-			Amount:              testCase.Amount,
-			IsSuccess:           scaleResult.IsSuccess,
-			ReturnedData:        scaledData,
-			InputData:           routeEncodedData.Data,
-			NewAmount:           newAmount.String(),
-			Route:               route.Route,
-			Error:               fmt.Sprintf("Scaled Tenderly simulation failed: %v", err),
-			OriginalTenderlyURL: originalTenderlyURL,
-		}, err
-	}
-
-	// Step 4: Check if scaled simulation failed - if so, this triggers alert
-	if !scaledSuccess {
+		return fail(err, fmt.Sprintf("Scaled %s simulation failed: %v", simulator, err))
+	}
+
+	base.ScaledTenderlyURL = scaled.URL
+	base.ScaledGasUsed = scaled.GasUsed
+
+	// Step 5: Check if scaled simulation failed - if so, this triggers alert
+	if !scaled.Success {
 		errorMsg := "Scaled swap simulation failed"
-		if scaledError != "" {
-			errorMsg = fmt.Sprintf("Scaled swap failed: %s", scaledError)
+		if scaled.ErrorMessage != "" {
+			errorMsg = fmt.Sprintf("Scaled swap failed: %s", scaled.ErrorMessage)
 		}
+		m.recordSimulationFailure(&base, scaled)
 
 		scaleErr := &CallGetScaledInputDataError{
 			ChainName: chainConfig.Name,
 			Message:   errorMsg,
 		}
 
-		return &Result{
-			ChainName:           chainConfig.Name,
-			TokenIn:             testCase.TokenIn,
-			TokenOut:            testCase.TokenOut,
-			Amount:              testCase.Amount,
-			IsSuccess:           false, // Scaled simulation failed
-			ReturnedData:        scaledData,
-			InputData:           routeEncodedData.Data,
-			NewAmount:           newAmount.String(),
-			Route:               route.Route,
-			Error:               errorMsg,
-			OriginalTenderlyURL: originalTenderlyURL,
-			ScaledTenderlyURL:   scaledTenderlyURL,
-		}, scaleErr
-	}
-
-	return &Result{
-		ChainName:           chainConfig.Name,
-		TokenIn:             testCase.TokenIn,
-		TokenOut:            testCase.TokenOut,
-		Amount:              testCase.Amount,
-		IsSuccess:           true,
-		ReturnedData:        scaledData,
-		InputData:           routeEncodedData.Data,
-		NewAmount:           newAmount.String(),
-		Route:               route.Route,
-		OriginalTenderlyURL: originalTenderlyURL,
-		ScaledTenderlyURL:   scaledTenderlyURL,
-	}, nil
+		return fail(scaleErr, errorMsg)
+	}
+
+	// Step 6: The swap executes, but the helper output may still break a property
+	if len(base.PropertyViolations) > 0 {
+		propertyErr := newPropertyViolationError(chainConfig.Name, base.PropertyViolations)
+		return fail(propertyErr, propertyErr.Error())
+	}
+
+	result := base
+	result.IsSuccess = true
+	return &result, nil
+}
+
+// scaleChoice is a change of the swap amount by up to 20%
+type scaleChoice struct {
+	down       bool
+	percentage int
+}
+
+// pickScale picks a random direction and percentage
+func pickScale() scaleChoice {
+	return scaleChoice{down: rand.Intn(1000000)%2 == 0, percentage: rand.Intn(20)}
+}
+
+// apply scales amount by the chosen percentage, always down when onlyDown is set
+func (s scaleChoice) apply(amount *big.Int, onlyDown bool) *big.Int {
+	if s.down || onlyDown {
+		newAmount := new(big.Int).Mul(amount, big.NewInt(int64(100-s.percentage)))
+		return newAmount.Div(newAmount, big.NewInt(100))
+	}
+	newAmount := new(big.Int).Mul(amount, big.NewInt(int64(100+s.percentage)))
+	return newAmount.Div(newAmount, big.NewInt(100))
+}
+
+// scaleAmount picks a random new amount within 20% of amount. Only smaller
+// amounts are picked when onlyDown is set.
+func scaleAmount(amount *big.Int, onlyDown bool) *big.Int {
+	return pickScale().apply(amount, onlyDown)
 }
 
-// callGetScaledInputData calls the getScaledInputData function on the contract
-func (m *Monitor) callGetScaledInputData(ctx context.Context, client *ethclient.Client, contractAddress string, inputData []byte, newAmount *big.Int) (*ContractCallResult, error) {
+// callGetScaledInputData calls the getScaledInputData function on the contract.
+// A nil blockNumber calls against the latest block.
+func (m *Monitor) callGetScaledInputData(ctx context.Context, client ContractCaller, contractAddress string, inputData []byte, newAmount *big.Int, blockNumber *big.Int) (*ContractCallResult, error) {
 	// Find the chain ID from the contract address
 	chainID, err := client.ChainID(ctx)
 	if err != nil {
@@ -462,11 +535,13 @@ func (m *Monitor) callGetScaledInputData(ctx context.Context, client *ethclient.
 	msg.To = &contractAddr
 
 	// Make the call
-	result, err := client.CallContract(ctx, msg, nil)
+	result, err := client.CallContract(ctx, msg, blockNumber)
 	if err != nil {
 		// Check if this is a contract revert vs RPC failure
 		errMsg := err.Error()
-		if strings.Contains(errMsg, "execution reverted") ||
+		revertData, hasRevertData := revert.FromError(err)
+		if hasRevertData ||
+			strings.Contains(errMsg, "execution reverted") ||
 			strings.Contains(errMsg, "revert") ||
 			strings.Contains(errMsg, "invalid opcode") ||
 			strings.Contains(errMsg, "out of gas") {
@@ -475,6 +550,10 @@ func (m *Monitor) callGetScaledInputData(ctx context.Context, client *ethclient.
 				ChainName: chainName,
 				Message:   errMsg,
 			}
+			if hasRevertData {
+				scaleErr.Data = hexutil.Encode(revertData)
+				scaleErr.Reason = m.revertDecoder.Decode(revertData).String()
+			}
 			return nil, scaleErr
 		}
 		// This is an RPC failure, return regular error
@@ -505,52 +584,26 @@ func (m *Monitor) callGetScaledInputData(ctx context.Context, client *ethclient.
 	return &ContractCallResult{
 		IsSuccess: isSuccess,
 		Data:      data,
+		Endpoint:  servedBy(client),
 	}, nil
 }
 
-// RunMonitoringOnce runs monitoring for all test cases once and exits
-func (m *Monitor) RunMonitoringOnce(ctx context.Context) error {
+// RunMonitoringOnce runs monitoring for all test cases once and returns the run record
+func (m *Monitor) RunMonitoringOnce(ctx context.Context) (*RunRecord, error) {
 	m.logger.Info("Running one-shot monitoring check")
 
-	var failures []slack.MonitoringResult
+	run, failures := m.runTestCases(ctx)
 
-	// Monitor each test case
-	for i, testCase := range m.testCases {
-		result, err := m.MonitorChain(ctx, testCase)
-		if err != nil {
-			// Only collect failures for CallGetScaledInputDataError (scale helper or simulation failures)
-			var scaleHelperErr *CallGetScaledInputDataError
-			if errors.As(err, &scaleHelperErr) && result != nil {
-				// Add to failures collection instead of sending individual alert
-				failures = append(failures, result)
-				m.logger.WithError(err).Error("Monitoring check failed")
-			} else {
-				// For other errors (API failures, network issues), just log
-				m.logger.WithError(err).Warn("Monitoring check encountered error")
-			}
-			continue
-		}
-
-		// Log the result
-		m.logger.WithFields(logrus.Fields{
-			"chain":    result.ChainName,
-			"tokenIn":  m.tokens[result.ChainName][result.TokenIn].Symbol,
-			"tokenOut": m.tokens[result.ChainName][result.TokenOut].Symbol,
-		}).Info(fmt.Sprintf("Test case %d completed", i+1))
-	}
-
-	if alertErr := m.slackClient.SendAlert(failures, len(m.testCases)); alertErr != nil {
-		m.logger.WithError(alertErr).Error("Failed to send Slack alert")
-	}
+	m.sendAlert(failures, run.Total)
 	m.logger.WithFields(logrus.Fields{
-		"Total test cases": len(m.testCases),
+		"Total test cases": run.Total,
 		"Run on chains":    len(m.chains),
 		"Failures":         len(failures),
-		"Success Rate":     fmt.Sprintf("%.2f%%", float64(len(m.testCases)-len(failures))/float64(len(m.testCases))*100),
+		"Success Rate":     fmt.Sprintf("%.2f%%", float64(run.Total-len(failures))/float64(run.Total)*100),
 	}).Info("Monitoring check completed")
 
 	m.logger.Info("One-shot monitoring completed")
-	return nil
+	return run, nil
 }
 
 // RunMonitoring runs the monitoring loop
@@ -562,6 +615,8 @@ func (m *Monitor) RunMonitoring(ctx context.Context) error {
 	}
 
 	m.logger.WithField("interval", interval).Info("Starting monitoring loop")
+	m.CheckRPCs(ctx)
+	m.CheckDeployments(ctx)
 
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
@@ -573,53 +628,253 @@ func (m *Monitor) RunMonitoring(ctx context.Context) error {
 			return ctx.Err()
 
 		case <-ticker.C:
-			var failures []slack.MonitoringResult
-
-			for i, testCase := range m.testCases {
-				result, err := m.MonitorChain(ctx, testCase)
-				if err != nil {
-					// Only collect failures for CallGetScaledInputDataError (scale helper or simulation failures)
-					var scaleHelperErr *CallGetScaledInputDataError
-					if errors.As(err, &scaleHelperErr) && result != nil {
-						failures = append(failures, result)
-						m.logger.WithError(err).Error("Monitoring check failed")
-					} else {
-						// For other errors (API failures, network issues), just log
-						m.logger.WithError(err).Warn("Monitoring check encountered error")
-					}
-					continue
-				}
-
-				// Log the result
-				m.logger.WithFields(logrus.Fields{
-					"chain":    result.ChainName,
-					"tokenIn":  m.tokens[result.ChainName][result.TokenIn].Symbol,
-					"tokenOut": m.tokens[result.ChainName][result.TokenOut].Symbol,
-				}).Info(fmt.Sprintf("Test case %d completed", i+1))
-			}
+			run, failures := m.runTestCases(ctx)
 
 			// Send batch alert if there are any failures
 			if len(failures) > 0 {
-				if alertErr := m.slackClient.SendAlert(failures, len(m.testCases)); alertErr != nil {
-					m.logger.WithError(alertErr).Error("Failed to send Slack alert")
-				}
+				m.sendAlert(failures, run.Total)
 				m.logger.WithFields(logrus.Fields{
-					"Total test cases": len(m.testCases),
+					"Total test cases": run.Total,
 					"Run on chains":    len(m.chains),
 					"Failures":         len(failures),
 				}).Info("Monitoring check completed")
 			} else {
 				m.logger.Info("All test cases passed successfully on all chains")
+				m.resolveAlert()
 			}
 		}
 	}
 }
 
+// sendAlert sends the batch alert for a run, unless running in dry-run mode
+func (m *Monitor) sendAlert(failures []slack.MonitoringResult, total int) {
+	if m.config.DryRun {
+		m.logger.WithField("failures", len(failures)).Info("Dry run, skipping Slack alert")
+		return
+	}
+
+	if alertErr := m.slackClient.SendAlert(failures, total); alertErr != nil {
+		m.logger.WithError(alertErr).Error("Failed to send Slack alert")
+	}
+}
+
+// resolveAlert closes the open Slack alert thread after a run without failures
+func (m *Monitor) resolveAlert() {
+	if m.config.DryRun {
+		return
+	}
+
+	if err := m.slackClient.ResolveAlert(len(m.testCases)); err != nil {
+		m.logger.WithError(err).Error("Failed to resolve Slack alert")
+	}
+}
+
+// runTestCases runs every test case once, records the run in the history file
+// and returns the run along with the failures that should be alerted on
+func (m *Monitor) runTestCases(ctx context.Context) (*RunRecord, []slack.MonitoringResult) {
+	run := &RunRecord{
+		ID:        time.Now().UTC().Format("20060102T150405Z"),
+		StartedAt: time.Now().UTC(),
+	}
+	m.resetSimulation()
+	run.RPCHealth = m.CheckRPCs(ctx)
+	run.Deployments = m.CheckDeployments(ctx)
+
+	var results []*Result
+	var failures []slack.MonitoringResult
+
+	// Monitor each test case, the environments of a case and size share one run
+	siblings := make(map[int]*siblingRun)
+	for i, testCase := range m.testCases {
+		if siblings[testCase.group] == nil {
+			siblings[testCase.group] = &siblingRun{scale: pickScale()}
+		}
+		testCase.siblings = siblings[testCase.group]
+
+		startedAt := time.Now()
+		result, err := m.MonitorChain(ctx, testCase)
+		if result != nil {
+			result.DurationMs = time.Since(startedAt).Milliseconds()
+		}
+		if err != nil {
+			// Only collect failures for CallGetScaledInputDataError (scale helper or simulation failures)
+			// and PropertyViolationError (helper output breaking a property check)
+			var scaleHelperErr *CallGetScaledInputDataError
+			var propertyErr *PropertyViolationError
+			if errors.As(err, &propertyErr) && result != nil {
+				result.FailureType = FailureTypeProperty
+				failures = append(failures, result)
+				m.logger.WithError(err).Error("Property check failed")
+			} else if errors.As(err, &scaleHelperErr) && result != nil {
+				// Add to failures collection instead of sending individual alert
+				result.FailureType = FailureTypeScale
+				failures = append(failures, result)
+				m.logger.WithError(err).Error("Monitoring check failed")
+			} else {
+				// For other errors (API failures, network issues), just log
+				if result != nil {
+					result.FailureType = FailureTypeInfra
+				}
+				run.Errors++
+				m.logger.WithError(err).Warn("Monitoring check encountered error")
+			}
+			if result != nil {
+				result.ID = fmt.Sprintf("%s-%d", run.ID, i+1)
+				results = append(results, result)
+			}
+			continue
+		}
+		result.ID = fmt.Sprintf("%s-%d", run.ID, i+1)
+		results = append(results, result)
+
+		// Log the result
+		m.logger.WithFields(logrus.Fields{
+			"chain":    result.ChainName,
+			"tokenIn":  m.tokens[result.ChainName][result.TokenIn].Symbol,
+			"tokenOut": m.tokens[result.ChainName][result.TokenOut].Symbol,
+			"size":     result.SizeBucket,
+		}).Info(fmt.Sprintf("Test case %d completed", i+1))
+	}
+
+	// Edge scenarios reuse the calldata and block of each test case
+	edgeCount := 0
+	caseResults := results
+	for _, base := range caseResults {
+		edges := m.runEdgeScenarios(ctx, base)
+		for _, edge := range edges {
+			edge.ID = fmt.Sprintf("%s-%s", base.ID, edge.Scenario)
+			switch edge.FailureType {
+			case FailureTypeScale:
+				failures = append(failures, edge)
+				m.logger.WithField("result", edge.ID).Error(edge.Error)
+			case FailureTypeInfra:
+				run.Errors++
+				m.logger.WithField("result", edge.ID).Warn(edge.Error)
+			}
+			results = append(results, edge)
+		}
+		if len(edges) > 0 {
+			edgeCount += len(edges)
+			m.logger.WithField("result", base.ID).Info(fmt.Sprintf("Edge scenarios completed, %s", edgeSummary(edges)))
+		}
+	}
+
+	run.FinishedAt = time.Now().UTC()
+	run.Total = len(m.testCases) + edgeCount
+	run.Failures = len(failures)
+	run.Results = results
+	run.SizeBuckets = summarizeSizeBuckets(results)
+	run.EnvironmentDiffs = summarizeEnvironmentDiffs(results)
+	run.NewEncodings = m.checkEncodings(results)
+	run.GasRegressions = m.checkGas(run)
+	run.Simulation = m.simulationUsage()
+
+	for _, bucket := range run.SizeBuckets {
+		if bucket.Failures == 0 {
+			continue
+		}
+		m.logger.WithFields(logrus.Fields{
+			"chain":    bucket.ChainName,
+			"size":     bucket.SizeBucket,
+			"failures": bucket.Failures,
+			"total":    bucket.Total,
+		}).Warn("Size bucket has failures")
+	}
+
+	if m.history != nil {
+		if err := m.history.SaveRun(run); err != nil {
+			m.logger.WithError(err).Error("Failed to save run history")
+		}
+	}
+
+	return run, failures
+}
+
+// checkEncodings reports the router encodings seen for the first time that are
+// not on the supported list, whether or not their test cases failed
+func (m *Monitor) checkEncodings(results []*Result) []EncodingDrift {
+	if m.encodings == nil {
+		return nil
+	}
+
+	drifts, err := m.encodings.Check(results, time.Now().UTC())
+	if err != nil {
+		m.logger.WithError(err).Error("Failed to check router encodings")
+	}
+	if len(drifts) == 0 {
+		return drifts
+	}
+
+	lines := make([]string, 0, len(drifts))
+	for _, drift := range drifts {
+		m.logger.WithFields(logrus.Fields{
+			"kind":  drift.Kind,
+			"value": drift.Value,
+			"chain": drift.ChainName,
+			"case":  drift.Case,
+		}).Warn("New router encoding")
+		lines = append(lines, fmt.Sprintf("%s `%s` on %s (case %s, result `%s`)", drift.Kind, drift.Value, drift.ChainName, drift.Case, drift.ResultID))
+	}
+
+	if m.config.DryRun {
+		m.logger.WithField("encodings", len(drifts)).Info("Dry run, skipping Slack encoding alert")
+		return drifts
+	}
+	title := fmt.Sprintf("⚠️ Scale Helper Monitor - %d new router encodings", len(drifts))
+	if err := m.slackClient.SendNotice(title, lines); err != nil {
+		m.logger.WithError(err).Error("Failed to send Slack encoding alert")
+	}
+	return drifts
+}
+
+// recordSimulationFailure records the decoded revert and the failing frame of a
+// failed simulation on the result
+func (m *Monitor) recordSimulationFailure(result *Result, simulation *tenderly.SwapSimulation) {
+	revertData, contract := simulation.RevertData, simulation.ErrorAddress
+	if failure := simulation.Failure; failure != nil {
+		result.FailureTrace = failure
+		// The deepest frame holds the original revert, before any wrapping
+		if failure.Output != "" && failure.Output != "0x" {
+			revertData = failure.Output
+		}
+		if contract == "" {
+			contract = failure.Contract
+		}
+	}
+
+	reason := m.revertDecoder.DecodeHex(revertData)
+	if reason.Kind == revert.KindEmpty && contract == "" {
+		return
+	}
+
+	result.RevertReason = reason.String()
+	result.RevertContract = contract
+}
+
+// marshalPayload renders a simulation request as indented JSON for alerts and history
+func marshalPayload(payload interface{}) string {
+	data, err := json.MarshalIndent(payload, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// findChain returns the configuration of a chain by name
+func (m *Monitor) findChain(chainName string) *ChainConfig {
+	for i := range m.chains {
+		if m.chains[i].Name == chainName {
+			return &m.chains[i]
+		}
+	}
+	return nil
+}
+
 // Close closes all connections
 func (m *Monitor) Close() {
 	for chainName, client := range m.ethClients {
-		if client != nil {
-			client.Close()
+		if closer, ok := client.(interface{ Close() }); ok {
+			closer.Close()
 			m.logger.WithField("chain", chainName).Info("Closed RPC connection")
 		}
 	}
@@ -0,0 +1,533 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sirupsen/logrus"
+
+	"scale-helper-monitor/internal/clients/chaintest"
+	"scale-helper-monitor/internal/clients/kyberswap"
+	"scale-helper-monitor/internal/clients/tenderly"
+)
+
+const (
+	testChain    = "testchain"
+	testRouter   = "0x6131B5fae19EA4f9D964eAc0408E4408b66337b5"
+	testTokenOut = "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"
+	testSwapGas  = 120000
+
+	testExecutor  = "0x63242A4Ea82847b20E506b63B0e2e2eFF0CC6cB0"
+	testRecipient = "0x2222222222222222222222222222222222222222"
+)
+
+var (
+	testRouterABI = mustRouterABI()
+	testAmount    = big.NewInt(1_500_000_000_000_000_000) // The test case amount, 1.5 ETH
+	testCase      = TestCase{ChainName: testChain, TokenIn: tenderly.NATIVE_ADDRESS, TokenOut: testTokenOut, Amount: "1.5"}
+
+	// testCalldata is the swapSimpleMode calldata the aggregator builds for testCase
+	testCalldata = mustPackSwap(swapDescription{
+		SrcToken:        common.HexToAddress(tenderly.NATIVE_ADDRESS),
+		DstToken:        common.HexToAddress(testTokenOut),
+		SrcReceivers:    []common.Address{testPool},
+		SrcAmounts:      []*big.Int{testAmount},
+		FeeReceivers:    []common.Address{},
+		FeeAmounts:      []*big.Int{},
+		DstReceiver:     common.HexToAddress(testRecipient),
+		Amount:          testAmount,
+		MinReturnAmount: big.NewInt(3_000_000_000),
+		Flags:           big.NewInt(0),
+		Permit:          []byte{},
+	})
+	testPool = common.HexToAddress("0x1")
+)
+
+func mustRouterABI() abi.ABI {
+	routerABI, err := createRouterABI()
+	if err != nil {
+		panic(err)
+	}
+	return routerABI
+}
+
+func mustPackSwap(desc swapDescription) []byte {
+	executorData, err := testRouterABI.Methods["simpleSwapData"].Inputs.Pack(simpleSwapData{
+		FirstPools:           []common.Address{testPool},
+		FirstSwapAmounts:     []*big.Int{desc.Amount},
+		SwapDatas:            [][]byte{},
+		Deadline:             big.NewInt(4_000_000_000),
+		PositiveSlippageData: []byte{},
+	})
+	if err != nil {
+		panic(err)
+	}
+	calldata, err := testRouterABI.Pack("swapSimpleMode", common.HexToAddress(testExecutor), desc, executorData, []byte{})
+	if err != nil {
+		panic(err)
+	}
+	return calldata
+}
+
+// rescaleSwap scales desc.amount and minReturnAmount of swapSimpleMode calldata
+// like the scale helper, then lets edit break the result
+func rescaleSwap(inputData []byte, newAmount *big.Int, edit func(desc *swapDescription)) ([]byte, error) {
+	method := testRouterABI.Methods["swapSimpleMode"]
+	args, err := method.Inputs.Unpack(inputData[4:])
+	if err != nil {
+		return nil, err
+	}
+	desc := *abi.ConvertType(args[1], new(swapDescription)).(*swapDescription)
+	minReturn := new(big.Int).Mul(desc.MinReturnAmount, newAmount)
+	desc.Amount, desc.MinReturnAmount = newAmount, minReturn.Div(minReturn, desc.Amount)
+	if edit != nil {
+		edit(&desc)
+	}
+	return testRouterABI.Pack("swapSimpleMode", args[0], desc, args[2], args[3])
+}
+
+// rescalingHelper is a mock scale helper that rescales the swap and rejects a zero amount
+func rescalingHelper(edit func(desc *swapDescription)) chaintest.ScaleFunc {
+	return func(inputData []byte, newAmount *big.Int) (bool, []byte, error) {
+		if newAmount.Sign() == 0 {
+			return false, nil, nil
+		}
+		data, err := rescaleSwap(inputData, newAmount, edit)
+		return err == nil, data, err
+	}
+}
+
+// testEnv runs MonitorChain against one in-process chain, with KyberSwap and
+// Tenderly served by httptest
+type testEnv struct {
+	monitor   *Monitor
+	failInput string // Swaps with this calldata fail in simulation
+}
+
+func newTestEnv(t *testing.T, config *Config, chainID int64, client ContractCaller, helper common.Address) *testEnv {
+	t.Helper()
+	env := &testEnv{}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	kyber := httptest.NewServer(http.HandlerFunc(serveKyberSwap))
+	t.Cleanup(kyber.Close)
+	simulator := httptest.NewServer(http.HandlerFunc(env.serveTenderly))
+	t.Cleanup(simulator.Close)
+
+	chains := []ChainConfig{{Name: testChain, ChainID: int(chainID), ContractAddress: helper.Hex()}}
+	tokens := map[string]map[string]TokenInfo{
+		testChain: {tenderly.NATIVE_ADDRESS: {Symbol: "ETH", Decimals: "18"}},
+	}
+	m, err := NewMonitor(
+		config,
+		[]TestCase{testCase},
+		tokens,
+		nil,
+		nil,
+		chains,
+		kyberswap.NewClient(kyberswap.Config{APIBaseURL: kyber.URL}, 5*time.Second, logger),
+		nil,
+		tenderly.NewClient("key", "user", "project", tenderly.Options{APIBaseURL: simulator.URL}, 5*time.Second),
+		logger,
+	)
+	if err != nil {
+		t.Fatalf("NewMonitor: %v", err)
+	}
+	m.ethClients[testChain] = client
+	env.monitor = m
+	return env
+}
+
+func (env *testEnv) run(t *testing.T) (*Result, error) {
+	t.Helper()
+	result, err := env.monitor.MonitorChain(context.Background(), env.monitor.testCases[0])
+	if result == nil {
+		t.Fatalf("MonitorChain returned no result, error %v", err)
+	}
+	return result, err
+}
+
+// serveKyberSwap returns a single-hop route whose build is testCalldata
+func serveKyberSwap(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/api/v1/routes"):
+		amountIn := r.URL.Query().Get("amountIn")
+		fmt.Fprintf(w, `{"code":0,"data":{"routerAddress":%q,"routeSummary":{"amountIn":%q,"route":[[{"pool":"0x1","exchange":"uniswap-v3","swapAmount":%q}]]}}}`,
+			testRouter, amountIn, amountIn)
+	case strings.HasSuffix(r.URL.Path, "/api/v1/route/build"):
+		var build struct {
+			RouteSummary kyberswap.KyberSwapRoute `json:"routeSummary"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&build); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"code":0,"data":{"amountIn":%q,"data":%q,"routerAddress":%q,"transactionValue":%q}}`,
+			build.RouteSummary.AmountIn, hexutil.Encode(testCalldata), testRouter, build.RouteSummary.AmountIn)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveTenderly simulates every bundle successfully, except swaps of failInput
+func (env *testEnv) serveTenderly(w http.ResponseWriter, r *http.Request) {
+	var bundle tenderly.SimulationBundleRequest
+	if err := json.NewDecoder(r.Body).Decode(&bundle); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var response tenderly.SimulationBundleResponse
+	for _, simulation := range bundle.Simulations {
+		var result tenderly.SimulationResult
+		result.Simulation.ID = "sim"
+		result.Transaction = &tenderly.SimulatedTransaction{GasUsed: testSwapGas, Status: true}
+		if strings.EqualFold(simulation.Input, env.failInput) {
+			result.Transaction.Status = false
+			result.Simulation.ErrorMessage = "execution reverted"
+		}
+		result.Simulation.Status = result.Transaction.Status
+		response.SimulationResults = append(response.SimulationResults, result)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func TestMonitorChainSimulatedHelper(t *testing.T) {
+	ctx := context.Background()
+	backend, err := chaintest.NewBackend()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+	helper, err := backend.DeployEchoHelper(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := newTestEnv(t, &Config{DryRun: true}, chaintest.SimulatedChainID, backend, helper)
+	result, err := env.run(t)
+	if err != nil {
+		t.Fatalf("MonitorChain failed: %v", err)
+	}
+	if !result.IsSuccess {
+		t.Errorf("result is not successful: %+v", result)
+	}
+	if result.ReturnedData != hexutil.Encode(testCalldata) {
+		t.Errorf("returned data %s, want the echoed input %s", result.ReturnedData, hexutil.Encode(testCalldata))
+	}
+	if result.BlockNumber != 1 {
+		t.Errorf("block number %d, want 1", result.BlockNumber)
+	}
+	if result.HelperGas == 0 {
+		t.Error("helper gas not recorded")
+	}
+	if result.OriginalGasUsed != testSwapGas || result.ScaledGasUsed != testSwapGas {
+		t.Errorf("swap gas %d/%d, want %d", result.OriginalGasUsed, result.ScaledGasUsed, testSwapGas)
+	}
+
+	checks := env.monitor.CheckDeployments(ctx)
+	if len(checks) != 1 || checks[0].CodeHash != crypto.Keccak256Hash(chaintest.EchoHelperCode).Hex() || len(checks[0].Problems) > 0 {
+		t.Errorf("unexpected deployment checks %+v", checks)
+	}
+}
+
+func TestSimulatedHelperRevertsOnZeroAmount(t *testing.T) {
+	ctx := context.Background()
+	backend, err := chaintest.NewBackend()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+	helper, err := backend.DeployEchoHelper(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := newTestEnv(t, &Config{DryRun: true}, chaintest.SimulatedChainID, backend, helper)
+	_, err = env.monitor.callGetScaledInputData(ctx, backend, helper.Hex(), testCalldata, big.NewInt(0), nil)
+	var scaleErr *CallGetScaledInputDataError
+	if !errors.As(err, &scaleErr) {
+		t.Fatalf("error %v, want a scale helper error", err)
+	}
+}
+
+func TestMonitorChainWithoutHelperCode(t *testing.T) {
+	backend, err := chaintest.NewBackend()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	env := newTestEnv(t, &Config{DryRun: true}, chaintest.SimulatedChainID, backend, common.HexToAddress("0x5ca1e"))
+	result, err := env.run(t)
+	var scaleErr *CallGetScaledInputDataError
+	if err == nil || errors.As(err, &scaleErr) {
+		t.Fatalf("error %v, want an infrastructure error", err)
+	}
+	if result.IsSuccess {
+		t.Error("result is successful")
+	}
+}
+
+func TestMonitorChainFake(t *testing.T) {
+	helper := common.HexToAddress("0x5ca1e")
+	scaled := append(append([]byte(nil), testCalldata...), 0xff)
+	reason, err := abi.NewType("string", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revertData, err := abi.Arguments{{Type: reason}}.Pack("amount too small")
+	if err != nil {
+		t.Fatal(err)
+	}
+	revertData = append(common.FromHex("0x08c379a0"), revertData...)
+
+	tests := []struct {
+		name         string
+		scale        chaintest.ScaleFunc
+		failInput    []byte
+		nodeErr      error
+		wantScaleErr bool
+		wantError    string
+		wantReason   string
+	}{
+		{
+			name:  "scaled swap succeeds",
+			scale: rescalingHelper(nil),
+		},
+		{
+			name: "helper returns false",
+			scale: func(inputData []byte, newAmount *big.Int) (bool, []byte, error) {
+				return false, nil, nil
+			},
+			wantScaleErr: true,
+			wantError:    "Scale helper returned false",
+		},
+		{
+			name: "helper reverts",
+			scale: func(inputData []byte, newAmount *big.Int) (bool, []byte, error) {
+				return false, nil, chaintest.Revert(revertData)
+			},
+			wantScaleErr: true,
+			wantError:    "Scale Failed",
+			wantReason:   "amount too small",
+		},
+		{
+			name: "scaled swap reverts",
+			scale: func(inputData []byte, newAmount *big.Int) (bool, []byte, error) {
+				return true, scaled, nil
+			},
+			failInput:    scaled,
+			wantScaleErr: true,
+			wantError:    "Scaled swap failed",
+		},
+		{
+			name: "original swap reverts",
+			scale: func(inputData []byte, newAmount *big.Int) (bool, []byte, error) {
+				return true, scaled, nil
+			},
+			failInput: testCalldata,
+			wantError: "Original swap failed",
+		},
+		{
+			name:      "node down",
+			nodeErr:   errors.New("connection refused"),
+			wantError: "Failed to get latest block",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := chaintest.NewFake(1, 100)
+			var newAmounts []*big.Int
+			if tt.scale != nil {
+				fake.Handle(helper, chaintest.ScaleHelper(func(inputData []byte, newAmount *big.Int) (bool, []byte, error) {
+					newAmounts = append(newAmounts, newAmount)
+					return tt.scale(inputData, newAmount)
+				}))
+			}
+			fake.Fail(tt.nodeErr)
+
+			env := newTestEnv(t, &Config{DryRun: true}, 1, fake, helper)
+			if tt.failInput != nil {
+				env.failInput = hexutil.Encode(tt.failInput)
+			}
+			result, err := env.run(t)
+
+			var scaleErr *CallGetScaledInputDataError
+			if isScaleErr := errors.As(err, &scaleErr); isScaleErr != tt.wantScaleErr {
+				t.Errorf("error %v, scale helper error %t, want %t", err, isScaleErr, tt.wantScaleErr)
+			}
+			if tt.wantError == "" {
+				if err != nil || !result.IsSuccess {
+					t.Fatalf("MonitorChain failed: %v", err)
+				}
+				returned, err := decodeRouterCall(testRouterABI, common.FromHex(result.ReturnedData))
+				if err != nil || returned.desc.Amount.String() != result.NewAmount {
+					t.Errorf("returned data %s does not swap the new amount %s: %v", result.ReturnedData, result.NewAmount, err)
+				}
+				if result.BlockNumber != 100 || result.HelperGas != chaintest.DefaultGas {
+					t.Errorf("block %d and helper gas %d, want 100 and %d", result.BlockNumber, result.HelperGas, chaintest.DefaultGas)
+				}
+			} else if !strings.Contains(result.Error, tt.wantError) {
+				t.Errorf("result error %q, want %q", result.Error, tt.wantError)
+			}
+			if !strings.Contains(result.RevertReason, tt.wantReason) {
+				t.Errorf("revert reason %q, want %q", result.RevertReason, tt.wantReason)
+			}
+
+			// The helper is called with the scaled amount the result reports
+			if len(newAmounts) > 0 && newAmounts[0].String() != result.NewAmount {
+				t.Errorf("helper called with %s, result reports %s", newAmounts[0], result.NewAmount)
+			}
+		})
+	}
+}
+
+func TestMonitorChainPropertyChecks(t *testing.T) {
+	helper := common.HexToAddress("0x5ca1e")
+	tests := []struct {
+		name string
+		edit func(desc *swapDescription)
+		want []string
+	}{
+		{
+			name: "helper keeps the properties",
+		},
+		{
+			name: "helper changes the recipient",
+			edit: func(desc *swapDescription) { desc.DstReceiver = common.HexToAddress("0xbad") },
+			want: []string{"identity", "invariants"},
+		},
+		{
+			name: "helper bumps the flags",
+			edit: func(desc *swapDescription) { desc.Flags = new(big.Int).Add(desc.Flags, big.NewInt(1)) },
+			want: []string{"identity"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := chaintest.NewFake(1, 100)
+			fake.Handle(helper, chaintest.ScaleHelper(rescalingHelper(tt.edit)))
+			config := &Config{DryRun: true, PropertyChecks: []string{"identity", "composition", "invariants"}}
+			env := newTestEnv(t, config, 1, fake, helper)
+			result, err := env.run(t)
+
+			var properties []string
+			for _, violation := range result.PropertyViolations {
+				properties = append(properties, violation.Property)
+			}
+			if strings.Join(properties, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("violated properties %v, want %v", properties, tt.want)
+			}
+
+			var propertyErr *PropertyViolationError
+			if isPropertyErr := errors.As(err, &propertyErr); isPropertyErr != (len(tt.want) > 0) {
+				t.Errorf("error %v, property error %t, want %t", err, isPropertyErr, len(tt.want) > 0)
+			}
+			if result.IsSuccess != (len(tt.want) == 0) {
+				t.Errorf("success %t with violations %v", result.IsSuccess, properties)
+			}
+		})
+	}
+}
+
+func TestRunTestCasesEdgeScenarios(t *testing.T) {
+	helper := common.HexToAddress("0x5ca1e")
+	tests := []struct {
+		name       string
+		scale      chaintest.ScaleFunc
+		wantFailed []string
+	}{
+		{
+			name:  "helper meets every expectation",
+			scale: rescalingHelper(nil),
+		},
+		{
+			name: "helper scales a zero amount",
+			scale: func(inputData []byte, newAmount *big.Int) (bool, []byte, error) {
+				data, err := rescaleSwap(inputData, newAmount, nil)
+				return err == nil, data, err
+			},
+			wantFailed: []string{EdgeZero},
+		},
+		{
+			name: "helper reverts on large amounts",
+			scale: func(inputData []byte, newAmount *big.Int) (bool, []byte, error) {
+				if newAmount.Cmp(new(big.Int).Mul(testAmount, big.NewInt(5))) > 0 {
+					return false, nil, chaintest.Revert(nil)
+				}
+				return rescalingHelper(nil)(inputData, newAmount)
+			},
+			wantFailed: []string{EdgeTenX},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := chaintest.NewFake(1, 100)
+			fake.Handle(helper, chaintest.ScaleHelper(tt.scale))
+			config := &Config{DryRun: true, EdgeScenarios: EdgeConfig{Enabled: true}}
+			env := newTestEnv(t, config, 1, fake, helper)
+			run, failures := env.monitor.runTestCases(context.Background())
+
+			var scenarios []string
+			for _, scenario := range edgeOrder {
+				if defaultEdgeExpectations[scenario] != ExpectSkip {
+					scenarios = append(scenarios, scenario)
+				}
+			}
+			if len(run.Results) != 1+len(scenarios) {
+				t.Fatalf("%d results, want the test case and %d edge scenarios", len(run.Results), len(scenarios))
+			}
+			if base := run.Results[0]; !base.IsSuccess || base.Scenario != "" {
+				t.Fatalf("test case result %+v, want a passing swap", base)
+			}
+
+			var failed []string
+			for i, edge := range run.Results[1:] {
+				if edge.Scenario != scenarios[i] {
+					t.Errorf("edge result %d is %s, want %s", i, edge.Scenario, scenarios[i])
+				}
+				if edge.BlockNumber != run.Results[0].BlockNumber {
+					t.Errorf("edge %s ran at block %d, want the test case block %d", edge.Scenario, edge.BlockNumber, run.Results[0].BlockNumber)
+				}
+				if edge.FailureType != "" {
+					if edge.FailureType != FailureTypeScale || edge.IsSuccess {
+						t.Errorf("edge %s failed as %s", edge.Scenario, edge.FailureType)
+					}
+					failed = append(failed, edge.Scenario)
+				}
+			}
+			if strings.Join(failed, ",") != strings.Join(tt.wantFailed, ",") {
+				t.Errorf("failed edge scenarios %v, want %v", failed, tt.wantFailed)
+			}
+			if len(failures) != len(tt.wantFailed) || run.Failures != len(tt.wantFailed) {
+				t.Errorf("%d failures alerted and %d recorded, want %d", len(failures), run.Failures, len(tt.wantFailed))
+			}
+
+			// Edge results stay out of the corpus and the size buckets
+			if entries := env.monitor.CorpusEntries(run.Results); len(entries) != 1 || entries[0].SourceResult != run.Results[0].ID {
+				t.Errorf("corpus entries %+v, want the test case result only", entries)
+			}
+			if len(run.SizeBuckets) != 1 || run.SizeBuckets[0].Total != 1 {
+				t.Errorf("size buckets %+v, want the test case result only", run.SizeBuckets)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/sirupsen/logrus"
+)
+
+// Rounding allowed between scaling twice and scaling once, per amount word
+const (
+	roundingToleranceWei = 16
+	roundingTolerancePPM = 10
+)
+
+// PropertyCheck asserts an invariant of getScaledInputData output. Check returns
+// nil when the property holds and an error when it could not be evaluated.
+type PropertyCheck interface {
+	Name() string
+	Check(ctx context.Context, input PropertyInput) (*PropertyViolation, error)
+}
+
+// PropertyInput describes one successful helper call
+type PropertyInput struct {
+	InputData      []byte
+	OriginalAmount *big.Int
+	NewAmount      *big.Int
+	Output         []byte // Calldata returned for NewAmount
+	RouterABI      abi.ABI
+
+	// Scale calls the same helper at the same block
+	Scale func(ctx context.Context, inputData []byte, newAmount *big.Int) (*ContractCallResult, error)
+}
+
+// PropertyViolation represents a property that does not hold on a helper result
+type PropertyViolation struct {
+	Property string `json:"property"`
+	Message  string `json:"message"`
+	Diff     string `json:"diff,omitempty"`
+}
+
+// Built-in property checks, by the name used in monitoring.property_checks
+var builtinPropertyChecks = map[string]PropertyCheck{
+	"identity":    identityCheck{},
+	"composition": compositionCheck{},
+	"invariants":  invariantsCheck{},
+}
+
+// newPropertyChecks resolves the configured property check names
+func newPropertyChecks(names []string) ([]PropertyCheck, error) {
+	checks := make([]PropertyCheck, 0, len(names))
+	for _, name := range names {
+		check, exists := builtinPropertyChecks[name]
+		if !exists {
+			return nil, fmt.Errorf("unknown property check %q", name)
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// AddPropertyCheck registers a property check on top of the configured ones
+func (m *Monitor) AddPropertyCheck(check PropertyCheck) {
+	m.properties = append(m.properties, check)
+}
+
+// checkProperties runs every property check on a successful helper result.
+// Checks that cannot be evaluated are logged and skipped.
+func (m *Monitor) checkProperties(ctx context.Context, input PropertyInput) []PropertyViolation {
+	var violations []PropertyViolation
+	for _, check := range m.properties {
+		violation, err := check.Check(ctx, input)
+		if err != nil {
+			m.logger.WithError(err).WithField("property", check.Name()).Warn("Property check could not run")
+			continue
+		}
+		if violation != nil {
+			violation.Property = check.Name()
+			m.logger.WithFields(logrus.Fields{
+				"property": violation.Property,
+				"diff":     violation.Diff,
+			}).Warn(violation.Message)
+			violations = append(violations, *violation)
+		}
+	}
+	return violations
+}
+
+// propertyScaleError tells helper reverts, which are violations, apart from RPC errors
+func propertyScaleError(err error, message string) (*PropertyViolation, error) {
+	var helperErr *CallGetScaledInputDataError
+	if errors.As(err, &helperErr) {
+		return &PropertyViolation{Message: message, Diff: helperErr.Error()}, nil
+	}
+	return nil, err
+}
+
+// identityCheck scales to the original amount, which must return the input unchanged
+type identityCheck struct{}
+
+func (identityCheck) Name() string { return "identity" }
+
+func (identityCheck) Check(ctx context.Context, input PropertyInput) (*PropertyViolation, error) {
+	result, err := input.Scale(ctx, input.InputData, input.OriginalAmount)
+	if err != nil {
+		return propertyScaleError(err, "Scaling to the same amount reverted")
+	}
+	if !result.IsSuccess {
+		return &PropertyViolation{Message: "Scaling to the same amount returned isSuccess=false"}, nil
+	}
+	if !bytes.Equal(result.Data, input.InputData) {
+		return &PropertyViolation{
+			Message: "Scaling to the same amount changed the calldata",
+			Diff:    calldataDiff(true, input.InputData, true, result.Data),
+		}, nil
+	}
+	return nil, nil
+}
+
+// compositionCheck scales the output again (A→B→C) and compares it with scaling
+// the input directly (A→C), allowing for rounding of the amounts
+type compositionCheck struct{}
+
+func (compositionCheck) Name() string { return "composition" }
+
+func (compositionCheck) Check(ctx context.Context, input PropertyInput) (*PropertyViolation, error) {
+	// C sits between A and B, or 5% below A when the amount was not scaled
+	target := new(big.Int).Add(input.OriginalAmount, input.NewAmount)
+	target.Div(target, big.NewInt(2))
+	if input.OriginalAmount.Cmp(input.NewAmount) == 0 {
+		target.Mul(input.OriginalAmount, big.NewInt(95))
+		target.Div(target, big.NewInt(100))
+	}
+
+	once, err := input.Scale(ctx, input.InputData, target)
+	if err != nil {
+		var helperErr *CallGetScaledInputDataError
+		if errors.As(err, &helperErr) {
+			return nil, nil // Scaling to C is not supported for this input at all
+		}
+		return nil, err
+	}
+	if !once.IsSuccess {
+		return nil, nil
+	}
+
+	twice, err := input.Scale(ctx, input.Output, target)
+	if err != nil {
+		return propertyScaleError(err, "Scaling the scaled calldata again reverted")
+	}
+	if !twice.IsSuccess {
+		return &PropertyViolation{Message: "Scaling the scaled calldata again returned isSuccess=false"}, nil
+	}
+
+	if diff := roundingDiff(once.Data, twice.Data); diff != "" {
+		return &PropertyViolation{
+			Message: fmt.Sprintf("Scaling twice differs from scaling once to %s", target),
+			Diff:    diff,
+		}, nil
+	}
+	return nil, nil
+}
+
+// roundingDiff compares calldata word by word, allowing differing words that
+// are amounts within rounding. It returns an empty string when they match.
+func roundingDiff(expected, actual []byte) string {
+	if len(expected) != len(actual) || len(expected) < 4 || !bytes.Equal(expected[:4], actual[:4]) {
+		return calldataDiff(true, expected, true, actual)
+	}
+
+	for start := 4; start < len(expected); start += 32 {
+		end := start + 32
+		if end > len(expected) {
+			end = len(expected)
+		}
+		if bytes.Equal(expected[start:end], actual[start:end]) {
+			continue
+		}
+
+		x := new(big.Int).SetBytes(expected[start:end])
+		y := new(big.Int).SetBytes(actual[start:end])
+		delta := new(big.Int).Sub(x, y)
+		delta.Abs(delta)
+
+		largest := x
+		if y.Cmp(x) > 0 {
+			largest = y
+		}
+		tolerance := new(big.Int).Mul(largest, big.NewInt(roundingTolerancePPM))
+		tolerance.Div(tolerance, big.NewInt(1_000_000))
+		if tolerance.Cmp(big.NewInt(roundingToleranceWei)) < 0 {
+			tolerance.SetInt64(roundingToleranceWei)
+		}
+		if delta.Cmp(tolerance) > 0 {
+			return fmt.Sprintf("word %d: %s, expected %s (off by %s)", (start-4)/32, y, x, delta)
+		}
+	}
+	return ""
+}
+
+// invariantsCheck checks that scaling keeps the selector, recipient and deadline
+type invariantsCheck struct{}
+
+func (invariantsCheck) Name() string { return "invariants" }
+
+func (invariantsCheck) Check(ctx context.Context, input PropertyInput) (*PropertyViolation, error) {
+	original, err := decodeRouterCall(input.RouterABI, input.InputData)
+	if err != nil {
+		return nil, nil // Only router swaps are checked
+	}
+	scaled, err := decodeRouterCall(input.RouterABI, input.Output)
+	if err != nil {
+		return &PropertyViolation{Message: "Scaled calldata is not a router swap", Diff: err.Error()}, nil
+	}
+
+	var diffs []string
+	if !bytes.Equal(original.selector, scaled.selector) {
+		diffs = append(diffs, fmt.Sprintf("selector %x → %x", original.selector, scaled.selector))
+	}
+	if original.desc.DstReceiver != scaled.desc.DstReceiver {
+		diffs = append(diffs, fmt.Sprintf("recipient %s → %s", original.desc.DstReceiver.Hex(), scaled.desc.DstReceiver.Hex()))
+	}
+
+	originalPayload, err := decodeExecutorPayload(input.RouterABI, original)
+	if err == nil {
+		scaledPayload, err := decodeExecutorPayload(input.RouterABI, scaled)
+		switch {
+		case err != nil:
+			diffs = append(diffs, "executor payload no longer decodes")
+		case originalPayload.deadline.Cmp(scaledPayload.deadline) != 0:
+			diffs = append(diffs, fmt.Sprintf("deadline %s → %s", originalPayload.deadline, scaledPayload.deadline))
+		}
+	}
+
+	if len(diffs) == 0 {
+		return nil, nil
+	}
+	return &PropertyViolation{Message: "Scaling changed fields that must stay unchanged", Diff: strings.Join(diffs, ", ")}, nil
+}
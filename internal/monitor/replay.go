@@ -0,0 +1,278 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"scale-helper-monitor/internal/clients/tenderly"
+)
+
+// ReplayReport compares a stored result with a fresh run of the same calldata
+type ReplayReport struct {
+	Stored      *Result
+	BlockNumber uint64 // 0 when replayed against the latest block
+	Helper      *ContractCallResult
+	HelperError string
+	Original    ReplaySimulation
+	Scaled      ReplaySimulation
+}
+
+// ReplaySimulation represents the outcome of one replayed simulation
+type ReplaySimulation struct {
+	Ran          bool
+	Success      bool
+	Error        string
+	RevertReason string
+	FailingFrame string
+	TenderlyURL  string
+}
+
+// LoadResultsFromFile loads a JSON dump of a single Result or a list of Results
+func LoadResultsFromFile(path string) ([]*Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result file: %v", err)
+	}
+
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var results []*Result
+		if err := json.Unmarshal(data, &results); err != nil {
+			return nil, fmt.Errorf("failed to parse results: %v", err)
+		}
+		return results, nil
+	}
+
+	var result Result
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse result: %v", err)
+	}
+	return []*Result{&result}, nil
+}
+
+// FindStoredResults resolves a run or result ID against the history file
+func (m *Monitor) FindStoredResults(id string) ([]*Result, error) {
+	if m.history == nil {
+		return nil, fmt.Errorf("monitoring.history_file is not configured")
+	}
+	return m.history.FindResults(id)
+}
+
+// FindStoredRun resolves a run ID against the history file, an empty ID is the latest run
+func (m *Monitor) FindStoredRun(id string) (*RunRecord, error) {
+	if m.history == nil {
+		return nil, fmt.Errorf("monitoring.history_file is not configured")
+	}
+	return m.history.FindRun(id)
+}
+
+// Replay re-runs the scale helper call and both simulations for a stored result.
+// When pinBlock is set the calls run against the block the result was recorded at.
+func (m *Monitor) Replay(ctx context.Context, stored *Result, pinBlock bool) (*ReplayReport, error) {
+	if stored.InputData == "" || stored.NewAmount == "" {
+		return nil, fmt.Errorf("result %s has no input data or new amount to replay", stored.ID)
+	}
+	if stored.RouterAddress == "" {
+		return nil, fmt.Errorf("result %s has no router address to simulate against", stored.ID)
+	}
+
+	chainConfig := m.findChain(stored.ChainName)
+	if chainConfig == nil {
+		return nil, fmt.Errorf("chain %s not found in configuration", stored.ChainName)
+	}
+
+	ethClient, exists := m.ethClients[chainConfig.Name]
+	if !exists {
+		return nil, fmt.Errorf("ethereum client not available for chain %s", chainConfig.Name)
+	}
+
+	inputData, err := hexutil.Decode(stored.InputData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode input data: %v", err)
+	}
+
+	newAmount, ok := new(big.Int).SetString(stored.NewAmount, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid new amount: %s", stored.NewAmount)
+	}
+
+	report := &ReplayReport{Stored: stored}
+
+	var blockNumber *big.Int
+	if pinBlock && stored.BlockNumber > 0 {
+		report.BlockNumber = stored.BlockNumber
+		blockNumber = new(big.Int).SetUint64(stored.BlockNumber)
+	}
+
+	// Step 1: Re-call the scale helper with the stored input
+	report.Helper, err = m.callGetScaledInputData(ctx, ethClient, chainConfig.ContractAddress, inputData, newAmount, blockNumber)
+	if err != nil {
+		report.HelperError = err.Error()
+	}
+
+	// Step 2: Re-simulate the original calldata
+	fromAddress := stored.Sender
+	if fromAddress == "" {
+		fromAddress = DefaultSender
+	}
+	report.Original = m.replaySimulation(ctx, chainConfig, stored, fromAddress, stored.InputData, stored.Amount, report.BlockNumber)
+
+	// Step 3: Re-simulate the scaled calldata returned by the helper
+	if report.Helper != nil && report.Helper.IsSuccess {
+		scaledData := hexutil.Encode(report.Helper.Data)
+		report.Scaled = m.replaySimulation(ctx, chainConfig, stored, fromAddress, scaledData, stored.NewAmount, report.BlockNumber)
+	}
+
+	return report, nil
+}
+
+// replaySimulation simulates calldata against the router of a stored result
+func (m *Monitor) replaySimulation(ctx context.Context, chainConfig *ChainConfig, stored *Result, fromAddress, data, amount string, blockNumber uint64) ReplaySimulation {
+	tokenIn := m.tokens[chainConfig.Name][stored.TokenIn]
+	stateObjects, err := m.tenderlyClient.CreateStateObjectsForSwap(
+		stored.TokenIn,
+		stored.RouterAddress,
+		fromAddress,
+		amount,
+		chainConfig.Name,
+		tokenIn.Storage(),
+	)
+	if err != nil {
+		return ReplaySimulation{Ran: true, Error: fmt.Sprintf("Failed to create state objects: %v", err)}
+	}
+
+	bundle := m.tenderlyClient.NewSwapBundle(
+		tenderly.GetChainNetworkID(chainConfig.ChainID),
+		stored.TokenIn,
+		fromAddress,
+		stored.RouterAddress,
+		data,
+		stored.TransactionValue,
+		stateObjects,
+		tenderly.NeedsApproval(stored.TokenIn, tokenIn.Storage()),
+		blockNumber,
+	)
+	simulation, simulator, err := m.simulate(ctx, bundle)
+	if err != nil {
+		return ReplaySimulation{Ran: true, Error: fmt.Sprintf("%s simulation failed: %v", simulator, err)}
+	}
+
+	replayed := ReplaySimulation{Ran: true, Success: simulation.Success, Error: simulation.ErrorMessage, TenderlyURL: simulation.URL}
+	if !simulation.Success {
+		replayed.RevertReason = m.revertDecoder.DecodeHex(simulation.RevertData).String()
+		if simulation.Failure != nil {
+			replayed.FailingFrame = simulation.Failure.Location()
+		}
+	}
+	return replayed
+}
+
+// Print writes a side-by-side comparison of the stored and replayed outcome
+func (r *ReplayReport) Print(w io.Writer) {
+	stored := r.Stored
+
+	replayBlock := "latest"
+	if r.BlockNumber > 0 {
+		replayBlock = fmt.Sprintf("%d", r.BlockNumber)
+	}
+
+	replayHelper := "error"
+	replayData := r.HelperError
+	if r.Helper != nil {
+		replayHelper = fmt.Sprintf("isSuccess=%t", r.Helper.IsSuccess)
+		replayData = summarizeCalldata(hexutil.Encode(r.Helper.Data))
+	}
+
+	dataMatches := "n/a"
+	if r.Helper != nil && stored.ReturnedData != "" {
+		dataMatches = fmt.Sprintf("%t", hexutil.Encode(r.Helper.Data) == stored.ReturnedData)
+	}
+
+	storedOutcome := "success"
+	if stored.Error != "" {
+		storedOutcome = stored.Error
+	}
+
+	fmt.Fprintf(w, "Replay of %s on %s (%s -> %s, size %s)\n", stored.ID, stored.ChainName, stored.TokenIn, stored.TokenOut, stored.SizeBucket)
+	fmt.Fprintf(w, "Router: %s\n\n", stored.RouterAddress)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\tSTORED\tREPLAY")
+	fmt.Fprintf(tw, "Block\t%d\t%s\n", stored.BlockNumber, replayBlock)
+	fmt.Fprintf(tw, "Amount / New amount\t%s / %s\t%s / %s\n", stored.Amount, stored.NewAmount, stored.Amount, stored.NewAmount)
+	fmt.Fprintf(tw, "Scale helper\t%s\t%s\n", summarizeHelper(stored), replayHelper)
+	fmt.Fprintf(tw, "Scaled calldata\t%s\t%s\n", summarizeCalldata(stored.ReturnedData), replayData)
+	fmt.Fprintf(tw, "Scaled calldata matches\t\t%s\n", dataMatches)
+	fmt.Fprintf(tw, "Original simulation\t%s\t%s\n", stored.OriginalTenderlyURL, r.Original.summary())
+	fmt.Fprintf(tw, "Scaled simulation\t%s\t%s\n", stored.ScaledTenderlyURL, r.Scaled.summary())
+	fmt.Fprintf(tw, "Outcome\t%s\t%s\n", storedOutcome, r.outcome())
+	tw.Flush()
+}
+
+// outcome summarizes the replayed run in the same terms as Result.Error
+func (r *ReplayReport) outcome() string {
+	switch {
+	case r.HelperError != "":
+		return fmt.Sprintf("Scale Failed: %s", r.HelperError)
+	case !r.Original.Success:
+		return fmt.Sprintf("Original swap failed: %s", r.Original.Error)
+	case !r.Helper.IsSuccess:
+		return "Scale helper returned false"
+	case !r.Scaled.Success:
+		return fmt.Sprintf("Scaled swap failed: %s", r.Scaled.Error)
+	default:
+		return "success"
+	}
+}
+
+func (s ReplaySimulation) summary() string {
+	if !s.Ran {
+		return "skipped"
+	}
+	status := "success"
+	if !s.Success {
+		status = "failed"
+		if s.Error != "" {
+			status = fmt.Sprintf("failed: %s", s.Error)
+		}
+		if s.RevertReason != "" {
+			status = fmt.Sprintf("%s (%s)", status, s.RevertReason)
+		}
+		if s.FailingFrame != "" {
+			status = fmt.Sprintf("%s in %s", status, s.FailingFrame)
+		}
+	}
+	if s.TenderlyURL == "" {
+		return status
+	}
+	return fmt.Sprintf("%s %s", status, s.TenderlyURL)
+}
+
+// summarizeHelper describes the stored scale helper outcome
+func summarizeHelper(result *Result) string {
+	if result.ReturnedData == "" {
+		return "not called or failed"
+	}
+	if result.Error == "Scale helper returned false" {
+		return "isSuccess=false"
+	}
+	return "isSuccess=true"
+}
+
+// summarizeCalldata shortens calldata to its length and hash for tabular output
+func summarizeCalldata(data string) string {
+	raw, err := hexutil.Decode(data)
+	if err != nil || len(raw) == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d bytes, keccak %s", len(raw), crypto.Keccak256Hash(raw).Hex()[:18])
+}
@@ -0,0 +1,236 @@
+package monitor
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// routerABIJSON holds the router swap functions, swap is 0xe21fd0e9 and
+// swapSimpleMode is 0x8af033fb. executorDescription, simpleSwapData and
+// swapSequence are not router functions, their inputs decode executor payloads.
+const routerABIJSON = `[
+	{"name":"swap","type":"function","stateMutability":"payable","inputs":[{"name":"execution","type":"tuple","components":[
+		{"name":"callTarget","type":"address"},
+		{"name":"approveTarget","type":"address"},
+		{"name":"targetData","type":"bytes"},
+		{"name":"desc","type":"tuple","components":[
+			{"name":"srcToken","type":"address"},
+			{"name":"dstToken","type":"address"},
+			{"name":"srcReceivers","type":"address[]"},
+			{"name":"srcAmounts","type":"uint256[]"},
+			{"name":"feeReceivers","type":"address[]"},
+			{"name":"feeAmounts","type":"uint256[]"},
+			{"name":"dstReceiver","type":"address"},
+			{"name":"amount","type":"uint256"},
+			{"name":"minReturnAmount","type":"uint256"},
+			{"name":"flags","type":"uint256"},
+			{"name":"permit","type":"bytes"}]},
+		{"name":"clientData","type":"bytes"}]}],"outputs":[]},
+	{"name":"swapSimpleMode","type":"function","stateMutability":"payable","inputs":[
+		{"name":"caller","type":"address"},
+		{"name":"desc","type":"tuple","components":[
+			{"name":"srcToken","type":"address"},
+			{"name":"dstToken","type":"address"},
+			{"name":"srcReceivers","type":"address[]"},
+			{"name":"srcAmounts","type":"uint256[]"},
+			{"name":"feeReceivers","type":"address[]"},
+			{"name":"feeAmounts","type":"uint256[]"},
+			{"name":"dstReceiver","type":"address"},
+			{"name":"amount","type":"uint256"},
+			{"name":"minReturnAmount","type":"uint256"},
+			{"name":"flags","type":"uint256"},
+			{"name":"permit","type":"bytes"}]},
+		{"name":"executorData","type":"bytes"},
+		{"name":"clientData","type":"bytes"}],"outputs":[]},
+	{"name":"executorDescription","type":"function","inputs":[{"name":"desc","type":"tuple","components":[
+		{"name":"swapSequences","type":"tuple[][]","components":[
+			{"name":"data","type":"bytes"},
+			{"name":"selectorAndFlags","type":"bytes32"}]},
+		{"name":"tokenIn","type":"address"},
+		{"name":"tokenOut","type":"address"},
+		{"name":"to","type":"address"},
+		{"name":"deadline","type":"uint256"},
+		{"name":"positiveSlippageData","type":"bytes"}]}],"outputs":[]},
+	{"name":"simpleSwapData","type":"function","inputs":[{"name":"data","type":"tuple","components":[
+		{"name":"firstPools","type":"address[]"},
+		{"name":"firstSwapAmounts","type":"uint256[]"},
+		{"name":"swapDatas","type":"bytes[]"},
+		{"name":"deadline","type":"uint256"},
+		{"name":"positiveSlippageData","type":"bytes"}]}],"outputs":[]},
+	{"name":"swapSequence","type":"function","inputs":[{"name":"swaps","type":"tuple[]","components":[
+		{"name":"data","type":"bytes"},
+		{"name":"selectorAndFlags","type":"bytes32"}]}],"outputs":[]}
+]`
+
+// createRouterABI creates the ABI used to decode router calldata
+func createRouterABI() (abi.ABI, error) {
+	return abi.JSON(strings.NewReader(routerABIJSON))
+}
+
+// swapDescription mirrors the router's SwapDescriptionV2
+type swapDescription struct {
+	SrcToken        common.Address
+	DstToken        common.Address
+	SrcReceivers    []common.Address
+	SrcAmounts      []*big.Int
+	FeeReceivers    []common.Address
+	FeeAmounts      []*big.Int
+	DstReceiver     common.Address
+	Amount          *big.Int
+	MinReturnAmount *big.Int
+	Flags           *big.Int
+	Permit          []byte
+}
+
+// swapExecution mirrors the router's SwapExecutionParams
+type swapExecution struct {
+	CallTarget    common.Address
+	ApproveTarget common.Address
+	TargetData    []byte
+	Desc          swapDescription
+	ClientData    []byte
+}
+
+// executorSwap mirrors one swap of an executor payload, the first 4 bytes of
+// SelectorAndFlags select the executor function that runs it
+type executorSwap struct {
+	Data             []byte
+	SelectorAndFlags [32]byte
+}
+
+// executorDescription mirrors the executor's SwapExecutorDescription
+type executorDescription struct {
+	SwapSequences        [][]executorSwap
+	TokenIn              common.Address
+	TokenOut             common.Address
+	To                   common.Address
+	Deadline             *big.Int
+	PositiveSlippageData []byte
+}
+
+// simpleSwapData mirrors the executor payload of simple mode swaps, each of
+// SwapDatas encodes a sequence of executorSwap
+type simpleSwapData struct {
+	FirstPools           []common.Address
+	FirstSwapAmounts     []*big.Int
+	SwapDatas            [][]byte
+	Deadline             *big.Int
+	PositiveSlippageData []byte
+}
+
+// routerCall represents decoded swap or swapSimpleMode calldata
+type routerCall struct {
+	method       string
+	selector     []byte
+	executor     common.Address // callTarget of swap, caller of swapSimpleMode
+	desc         swapDescription
+	executorData []byte // targetData of swap, executorData of swapSimpleMode
+}
+
+// decodeRouterCall decodes swap or swapSimpleMode calldata
+func decodeRouterCall(routerABI abi.ABI, calldata []byte) (*routerCall, error) {
+	if len(calldata) < 4 {
+		return nil, fmt.Errorf("calldata too short")
+	}
+	method, err := routerABI.MethodById(calldata[:4])
+	if err != nil {
+		return nil, err
+	}
+	args, err := method.Inputs.Unpack(calldata[4:])
+	if err != nil {
+		return nil, err
+	}
+
+	call := &routerCall{method: method.Name, selector: calldata[:4]}
+	switch method.Name {
+	case "swap":
+		execution, ok := abi.ConvertType(args[0], new(swapExecution)).(*swapExecution)
+		if !ok {
+			return nil, fmt.Errorf("unexpected swap arguments")
+		}
+		call.executor, call.desc, call.executorData = execution.CallTarget, execution.Desc, execution.TargetData
+	case "swapSimpleMode":
+		desc, ok := abi.ConvertType(args[1], new(swapDescription)).(*swapDescription)
+		if !ok {
+			return nil, fmt.Errorf("unexpected swapSimpleMode arguments")
+		}
+		executorData, _ := args[2].([]byte)
+		call.executor, call.desc, call.executorData = args[0].(common.Address), *desc, executorData
+	default:
+		return nil, fmt.Errorf("%s is not a router swap", method.Name)
+	}
+	return call, nil
+}
+
+// executorPayload holds what the monitor reads from an executor payload
+type executorPayload struct {
+	functions []string // Executor function of each swap, in route order
+	deadline  *big.Int
+}
+
+// decodeExecutorPayload decodes the executor payload of a router call. Swap mode
+// carries a SwapExecutorDescription after the executor selector, simple mode a
+// simpleSwapData.
+func decodeExecutorPayload(routerABI abi.ABI, call *routerCall) (*executorPayload, error) {
+	if call.method == "swap" && len(call.executorData) >= 4 {
+		if payload, err := decodeExecutorDescription(routerABI, call.executorData[4:]); err == nil {
+			return payload, nil
+		}
+	}
+	return decodeSimpleSwapData(routerABI, call.executorData)
+}
+
+func decodeExecutorDescription(routerABI abi.ABI, data []byte) (*executorPayload, error) {
+	args, err := routerABI.Methods["executorDescription"].Inputs.Unpack(data)
+	if err != nil {
+		return nil, err
+	}
+	desc, ok := abi.ConvertType(args[0], new(executorDescription)).(*executorDescription)
+	if !ok {
+		return nil, fmt.Errorf("unexpected executor description")
+	}
+
+	payload := &executorPayload{deadline: desc.Deadline}
+	for _, sequence := range desc.SwapSequences {
+		payload.functions = append(payload.functions, executorFunctions(sequence)...)
+	}
+	return payload, nil
+}
+
+func decodeSimpleSwapData(routerABI abi.ABI, data []byte) (*executorPayload, error) {
+	args, err := routerABI.Methods["simpleSwapData"].Inputs.Unpack(data)
+	if err != nil {
+		return nil, err
+	}
+	simple, ok := abi.ConvertType(args[0], new(simpleSwapData)).(*simpleSwapData)
+	if !ok {
+		return nil, fmt.Errorf("unexpected simple swap data")
+	}
+
+	payload := &executorPayload{deadline: simple.Deadline}
+	for _, swapData := range simple.SwapDatas {
+		args, err := routerABI.Methods["swapSequence"].Inputs.Unpack(swapData)
+		if err != nil {
+			return nil, err
+		}
+		sequence, ok := abi.ConvertType(args[0], new([]executorSwap)).(*[]executorSwap)
+		if !ok {
+			return nil, fmt.Errorf("unexpected swap sequence")
+		}
+		payload.functions = append(payload.functions, executorFunctions(*sequence)...)
+	}
+	return payload, nil
+}
+
+func executorFunctions(sequence []executorSwap) []string {
+	functions := make([]string, 0, len(sequence))
+	for _, swap := range sequence {
+		functions = append(functions, hexutil.Encode(swap.SelectorAndFlags[:4]))
+	}
+	return functions
+}
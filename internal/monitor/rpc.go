@@ -0,0 +1,110 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"scale-helper-monitor/internal/clients/rpcpool"
+)
+
+// RPCConfig configures the health checks of the RPC endpoints
+type RPCConfig struct {
+	MaxHeadAge string `mapstructure:"max_head_age"` // Endpoints whose latest block is older are unhealthy, rpcpool.DefaultMaxHeadAge when empty
+}
+
+// HeadAge returns the configured head age limit, rpcpool.DefaultMaxHeadAge when empty
+func (c RPCConfig) HeadAge() (time.Duration, error) {
+	if c.MaxHeadAge == "" {
+		return rpcpool.DefaultMaxHeadAge, nil
+	}
+	age, err := time.ParseDuration(c.MaxHeadAge)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rpc.max_head_age %q: %v", c.MaxHeadAge, err)
+	}
+	if age <= 0 {
+		return 0, fmt.Errorf("rpc.max_head_age must be positive, got %s", c.MaxHeadAge)
+	}
+	return age, nil
+}
+
+// Validate checks the head age limit
+func (c RPCConfig) Validate() error {
+	_, err := c.HeadAge()
+	return err
+}
+
+// ChainRPCHealth represents the health of the RPC endpoints of a chain
+type ChainRPCHealth struct {
+	ChainName string           `json:"chain_name"`
+	Endpoints []rpcpool.Health `json:"endpoints"`
+}
+
+// healthy tells whether at least one endpoint of the chain is healthy
+func (h ChainRPCHealth) healthy() bool {
+	for _, endpoint := range h.Endpoints {
+		if endpoint.Healthy {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckRPCs checks the chain ID and head age of every RPC endpoint and sends a
+// Slack notice for the chains whose endpoints just became all unhealthy
+func (m *Monitor) CheckRPCs(ctx context.Context) []ChainRPCHealth {
+	var healths []ChainRPCHealth
+	var lines []string
+	for _, chain := range m.chains {
+		checker, ok := m.ethClients[chain.Name].(healthChecker)
+		if !ok {
+			continue
+		}
+
+		health := ChainRPCHealth{ChainName: chain.Name, Endpoints: checker.Check(ctx)}
+		healths = append(healths, health)
+		for _, endpoint := range health.Endpoints {
+			if !endpoint.Healthy {
+				m.logger.WithFields(logrus.Fields{
+					"chain":    chain.Name,
+					"endpoint": endpoint.Endpoint,
+				}).Warn(fmt.Sprintf("Unhealthy RPC endpoint: %s", endpoint.Error))
+			}
+		}
+
+		if health.healthy() {
+			if m.rpcDown[chain.Name] {
+				m.logger.WithField("chain", chain.Name).Info("RPC endpoints recovered")
+			}
+			m.rpcDown[chain.Name] = false
+			continue
+		}
+		if m.rpcDown[chain.Name] {
+			continue
+		}
+		m.rpcDown[chain.Name] = true
+
+		errs := make([]string, 0, len(health.Endpoints))
+		for _, endpoint := range health.Endpoints {
+			errs = append(errs, fmt.Sprintf("%s: %s", endpoint.Endpoint, endpoint.Error))
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", chain.Name, strings.Join(errs, "; ")))
+	}
+
+	if len(lines) == 0 {
+		return healths
+	}
+	m.logger.WithField("chains", len(lines)).Error("All RPC endpoints of a chain are unhealthy")
+	if m.config.DryRun {
+		m.logger.WithField("chains", len(lines)).Info("Dry run, skipping Slack RPC alert")
+		return healths
+	}
+	title := fmt.Sprintf("📡 Scale Helper Monitor - no healthy RPC endpoint on %d chains", len(lines))
+	if err := m.slackClient.SendNotice(title, lines); err != nil {
+		m.logger.WithError(err).Error("Failed to send Slack RPC alert")
+	}
+	return healths
+}
@@ -0,0 +1,92 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"scale-helper-monitor/internal/clients/rpcsim"
+	"scale-helper-monitor/internal/clients/tenderly"
+)
+
+// Simulator runs the swap bundles of a test case
+type Simulator interface {
+	Name() string
+	SimulateSwapBundle(ctx context.Context, bundleReq *tenderly.SimulationBundleRequest) (*tenderly.SwapSimulation, error)
+}
+
+// tenderlyBackend is the name of the primary simulator
+const tenderlyBackend = "tenderly"
+
+// SimulationUsage represents the simulator usage of a run
+type SimulationUsage struct {
+	Backend             string     `json:"backend"`                   // Backend that ran the last simulation of the run
+	TenderlySimulations int        `json:"tenderly_simulations"`      // Tenderly simulations of this run
+	TenderlyToday       int        `json:"tenderly_today"`            // Tenderly simulations on the current UTC day
+	DailyLimit          int        `json:"daily_limit,omitempty"`     // Configured Tenderly daily limit
+	FallbackReason      string     `json:"fallback_reason,omitempty"` // Why the run switched to the fallback simulator
+	FallbackAt          *time.Time `json:"fallback_at,omitempty"`
+}
+
+// newFallbackSimulator creates the simulator used once the Tenderly quota runs out.
+// An empty name disables the fallback.
+func newFallbackSimulator(name string, chains []ChainConfig, ethClients map[string]ContractCaller) (Simulator, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case rpcsim.Name:
+		nodes := make(map[string]rpcsim.Node)
+		for _, chain := range chains {
+			if node, ok := ethClients[chain.Name].(rpcsim.Node); ok {
+				nodes[tenderly.GetChainNetworkID(chain.ChainID)] = node
+			}
+		}
+		return rpcsim.NewClient(nodes), nil
+	default:
+		return nil, fmt.Errorf("unknown fallback simulator %q, expected %q", name, rpcsim.Name)
+	}
+}
+
+// simulate runs a bundle on Tenderly, switching to the fallback simulator for
+// the rest of the run once Tenderly reports an exhausted quota. It returns the
+// name of the backend that ran the bundle.
+func (m *Monitor) simulate(ctx context.Context, bundleReq *tenderly.SimulationBundleRequest) (*tenderly.SwapSimulation, string, error) {
+	if m.simulation.FallbackAt == nil {
+		simulation, err := m.tenderlyClient.SimulateSwapBundle(ctx, bundleReq)
+		var quotaErr *tenderly.QuotaError
+		if !errors.As(err, &quotaErr) || m.fallbackSimulator == nil {
+			return simulation, tenderlyBackend, err
+		}
+
+		now := time.Now().UTC()
+		m.simulation.FallbackReason = quotaErr.Error()
+		m.simulation.FallbackAt = &now
+		m.simulation.Backend = m.fallbackSimulator.Name()
+		m.logger.WithFields(logrus.Fields{
+			"reason":   quotaErr.Error(),
+			"fallback": m.fallbackSimulator.Name(),
+		}).Warn("Tenderly quota exhausted, switching simulator for the rest of the run")
+	}
+
+	simulation, err := m.fallbackSimulator.SimulateSwapBundle(ctx, bundleReq)
+	return simulation, m.fallbackSimulator.Name(), err
+}
+
+// resetSimulation starts a run on Tenderly again
+func (m *Monitor) resetSimulation() {
+	m.tenderlyClient.ResetRunUsage()
+	m.simulation = &SimulationUsage{Backend: tenderlyBackend}
+}
+
+// simulationUsage returns the simulator usage of the current run
+func (m *Monitor) simulationUsage() *SimulationUsage {
+	usage := *m.simulation
+	tenderlyUsage := m.tenderlyClient.Usage()
+	usage.TenderlySimulations = tenderlyUsage.Run
+	usage.TenderlyToday = tenderlyUsage.Today
+	usage.DailyLimit = tenderlyUsage.DailyLimit
+	return &usage
+}
@@ -0,0 +1,127 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+
+	"scale-helper-monitor/internal/storage"
+)
+
+// SlotDiscovery represents a mapping found by DiscoverBalanceSlot or DiscoverAllowanceSlot.
+// Layout, Namespace and Index are the tokens.json layout, namespace and base slot.
+type SlotDiscovery struct {
+	Layout    string `json:"layout"`
+	Namespace string `json:"namespace,omitempty"`
+	Index     int64  `json:"index"`
+	Slot      string `json:"slot"` // Storage slot of the holder's balance or allowance
+}
+
+// openZeppelinNamespace is the ERC-7201 namespace of OpenZeppelin's upgradeable
+// ERC20, with balances at offset 0 and allowances at offset 1
+const openZeppelinNamespace = "openzeppelin.storage.ERC20"
+
+// balanceProbe is the value written to candidate slots while probing
+var balanceProbe = common.HexToHash("0x00000000000000000000000000000000000000000000000000000000c0ffee42")
+
+// DiscoverBalanceSlot finds the storage slot holding holder's token balance by
+// overriding candidate mapping slots in eth_call and checking balanceOf
+func DiscoverBalanceSlot(ctx context.Context, client *ethclient.Client, token, holder string, maxIndex int64) (*SlotDiscovery, error) {
+	erc20ABI, err := abi.JSON(strings.NewReader(`[{"inputs":[{"name":"account","type":"address"}],"name":"balanceOf","outputs":[{"name":"","type":"uint256"}],"stateMutability":"view","type":"function"}]`))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ERC20 ABI: %v", err)
+	}
+
+	callData, err := erc20ABI.Pack("balanceOf", common.HexToAddress(holder))
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack balanceOf: %v", err)
+	}
+
+	discovery, err := discoverSlot(ctx, client, token, callData, maxIndex, 0, func(layout storage.TokenLayout, index string) (string, error) {
+		layout.BalanceSlot = index
+		return layout.BalanceOf(holder)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("balanceOf call failed: %v", err)
+	}
+	if discovery == nil {
+		return nil, fmt.Errorf("no balance slot found for %s within mapping indexes 0-%d", token, maxIndex)
+	}
+	return discovery, nil
+}
+
+// DiscoverAllowanceSlot finds the storage slot holding owner's allowance to
+// spender by overriding candidate nested mapping slots in eth_call and checking allowance
+func DiscoverAllowanceSlot(ctx context.Context, client *ethclient.Client, token, owner, spender string, maxIndex int64) (*SlotDiscovery, error) {
+	erc20ABI, err := abi.JSON(strings.NewReader(`[{"inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"}],"name":"allowance","outputs":[{"name":"","type":"uint256"}],"stateMutability":"view","type":"function"}]`))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ERC20 ABI: %v", err)
+	}
+
+	callData, err := erc20ABI.Pack("allowance", common.HexToAddress(owner), common.HexToAddress(spender))
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack allowance: %v", err)
+	}
+
+	discovery, err := discoverSlot(ctx, client, token, callData, maxIndex, 1, func(layout storage.TokenLayout, index string) (string, error) {
+		layout.AllowanceSlot = index
+		return layout.AllowanceOf(owner, spender)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("allowance call failed: %v", err)
+	}
+	if discovery == nil {
+		return nil, fmt.Errorf("no allowance slot found for %s within mapping indexes 0-%d", token, maxIndex)
+	}
+	return discovery, nil
+}
+
+// discoverSlot probes the Solidity and Vyper mapping indexes up to maxIndex,
+// then the OpenZeppelin ERC-7201 namespace at namespaceOffset. It returns nil
+// when callData never reads back the probe value.
+func discoverSlot(ctx context.Context, client *ethclient.Client, token string, callData []byte, maxIndex, namespaceOffset int64, slotOf func(storage.TokenLayout, string) (string, error)) (*SlotDiscovery, error) {
+	type candidate struct {
+		layout storage.TokenLayout
+		index  int64
+	}
+
+	var candidates []candidate
+	for index := int64(0); index <= maxIndex; index++ {
+		for _, layout := range []string{storage.LayoutSolidity, storage.LayoutVyper} {
+			candidates = append(candidates, candidate{layout: storage.TokenLayout{Layout: layout}, index: index})
+		}
+	}
+	candidates = append(candidates, candidate{
+		layout: storage.TokenLayout{Layout: storage.LayoutERC7201, Namespace: openZeppelinNamespace},
+		index:  namespaceOffset,
+	})
+
+	tokenAddr := common.HexToAddress(token)
+	geth := gethclient.New(client.Client())
+	for _, c := range candidates {
+		slot, err := slotOf(c.layout, big.NewInt(c.index).String())
+		if err != nil {
+			return nil, err
+		}
+
+		overrides := map[common.Address]gethclient.OverrideAccount{
+			tokenAddr: {StateDiff: map[common.Hash]common.Hash{common.HexToHash(slot): balanceProbe}},
+		}
+		result, err := geth.CallContract(ctx, ethereum.CallMsg{To: &tokenAddr, Data: callData}, nil, &overrides)
+		if err != nil {
+			return nil, err
+		}
+
+		if common.BytesToHash(result) == balanceProbe {
+			return &SlotDiscovery{Layout: c.layout.Layout, Namespace: c.layout.Namespace, Index: c.index, Slot: slot}, nil
+		}
+	}
+	return nil, nil
+}
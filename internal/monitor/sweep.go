@@ -0,0 +1,161 @@
+package monitor
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// expandTestCases turns every configured test case into one sub-case per
+// swap size and aggregator environment. A test case may list a single `amount`,
+// an `amounts` list and/or a log-scale `amount_range`; all of them are merged
+// and de-duplicated.
+func expandTestCases(testCases []TestCase, tokens map[string]map[string]TokenInfo) ([]TestCase, error) {
+	var expanded []TestCase
+	group := 0
+	for _, testCase := range testCases {
+		amounts, err := testCaseAmounts(testCase)
+		if err != nil {
+			return nil, fmt.Errorf("invalid amounts for %s %s->%s: %w", testCase.ChainName, testCase.TokenIn, testCase.TokenOut, err)
+		}
+
+		environments := testCase.Environments
+		if len(environments) == 0 {
+			environments = []string{""}
+		}
+
+		symbol := tokens[testCase.ChainName][testCase.TokenIn].Symbol
+		for _, amount := range amounts {
+			group++
+			for _, environment := range environments {
+				subCase := testCase
+				subCase.Amount = amount
+				subCase.Amounts = nil
+				subCase.AmountRange = nil
+				subCase.SizeBucket = sizeBucketLabel(amount, symbol)
+				subCase.Environment = environment
+				subCase.group = group
+				expanded = append(expanded, subCase)
+			}
+		}
+	}
+
+	return expanded, nil
+}
+
+// siblingRun is what the environment sub-cases of one test case and size share
+// within a run, so their results differ only by environment
+type siblingRun struct {
+	scale scaleChoice // Amount change applied to every environment's route
+	block uint64      // Block pinned by the first sibling, 0 until then
+}
+
+// testCaseAmounts returns the human-readable swap sizes configured for a test case
+func testCaseAmounts(testCase TestCase) ([]string, error) {
+	candidates := []string{}
+	if testCase.Amount != "" {
+		candidates = append(candidates, testCase.Amount)
+	}
+	candidates = append(candidates, testCase.Amounts...)
+
+	if testCase.AmountRange != nil {
+		rangeAmounts, err := logScaleAmounts(*testCase.AmountRange)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, rangeAmounts...)
+	}
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no amount configured")
+	}
+
+	seen := make(map[string]bool)
+	var amounts []string
+	for _, amount := range candidates {
+		amount = strings.TrimSpace(amount)
+		if _, ok := new(big.Rat).SetString(amount); !ok {
+			return nil, fmt.Errorf("invalid amount format: %s", amount)
+		}
+		if seen[amount] {
+			continue
+		}
+		seen[amount] = true
+		amounts = append(amounts, amount)
+	}
+
+	return amounts, nil
+}
+
+// logScaleAmounts spreads Steps amounts evenly on a log scale between Min and Max
+// (inclusive), rounded to 3 significant digits so size buckets stay readable
+func logScaleAmounts(amountRange AmountRange) ([]string, error) {
+	minAmount, err := strconv.ParseFloat(amountRange.Min, 64)
+	if err != nil || minAmount <= 0 {
+		return nil, fmt.Errorf("invalid amount_range.min: %s", amountRange.Min)
+	}
+	maxAmount, err := strconv.ParseFloat(amountRange.Max, 64)
+	if err != nil || maxAmount < minAmount {
+		return nil, fmt.Errorf("invalid amount_range.max: %s", amountRange.Max)
+	}
+	if amountRange.Steps < 2 {
+		return nil, fmt.Errorf("amount_range.steps must be at least 2, got %d", amountRange.Steps)
+	}
+
+	ratio := maxAmount / minAmount
+	amounts := make([]string, 0, amountRange.Steps)
+	for i := 0; i < amountRange.Steps; i++ {
+		value := minAmount * math.Pow(ratio, float64(i)/float64(amountRange.Steps-1))
+		rounded, _ := strconv.ParseFloat(strconv.FormatFloat(value, 'g', 3, 64), 64)
+		amounts = append(amounts, strconv.FormatFloat(rounded, 'f', -1, 64))
+	}
+
+	return amounts, nil
+}
+
+// toBaseUnits converts a human-readable token amount into the token's smallest unit
+func toBaseUnits(amount string, decimals int64) (*big.Int, error) {
+	value, ok := new(big.Rat).SetString(amount)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount format: %s", amount)
+	}
+
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(decimals), nil)
+	value.Mul(value, new(big.Rat).SetInt(scale))
+
+	baseUnits := new(big.Int).Quo(value.Num(), value.Denom())
+	if baseUnits.Sign() <= 0 {
+		return nil, fmt.Errorf("amount %s rounds to zero with %d decimals", amount, decimals)
+	}
+
+	return baseUnits, nil
+}
+
+// sizeBucketLabel builds the label used to group results by swap size, e.g. "50k USDC"
+func sizeBucketLabel(amount, symbol string) string {
+	value, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return strings.TrimSpace(amount + " " + symbol)
+	}
+
+	units := []struct {
+		threshold float64
+		suffix    string
+	}{
+		{1e9, "B"},
+		{1e6, "M"},
+		{1e3, "k"},
+	}
+
+	label := strconv.FormatFloat(value, 'f', -1, 64)
+	for _, unit := range units {
+		if value >= unit.threshold {
+			label = strconv.FormatFloat(value/unit.threshold, 'f', -1, 64) + unit.suffix
+			break
+		}
+	}
+
+	return strings.TrimSpace(label + " " + symbol)
+}
@@ -0,0 +1,464 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/sirupsen/logrus"
+
+	"scale-helper-monitor/internal/clients/slack"
+	"scale-helper-monitor/internal/clients/tenderly"
+)
+
+// Traffic sampling defaults, used when monitoring.traffic leaves them unset
+const (
+	DefaultTrafficRouter     = "0x6131B5fae19EA4f9D964eAc0408E4408b66337b5"
+	defaultTrafficBlocks     = 200
+	defaultTrafficMaxSamples = 20
+	defaultTrafficMaxPerDex  = 3
+	trafficLogBlocks         = 1000 // Blocks per eth_getLogs query, within common provider limits
+)
+
+// TrafficConfig controls which recent router swaps are sampled for replay
+type TrafficConfig struct {
+	RouterAddress string `mapstructure:"router_address"` // Router whose transactions are scanned, DefaultTrafficRouter when empty
+	Blocks        uint64 `mapstructure:"blocks"`         // Blocks scanned back from the latest block
+	FromBlock     uint64 `mapstructure:"from_block"`     // Fixed block range, overrides blocks when set
+	ToBlock       uint64 `mapstructure:"to_block"`       // Last block of the range, the latest block when 0
+	MaxSamples    int    `mapstructure:"max_samples"`    // Swaps sampled per chain
+	MaxPerDex     int    `mapstructure:"max_per_dex"`    // Swaps sampled per combination of dex events
+}
+
+// swappedTopic is the event the router emits for every successful swap
+var swappedTopic = crypto.Keccak256Hash([]byte("Swapped(address,address,address,address,uint256,uint256)"))
+
+// Events left out of the dex signature of a swap, they show up on every route
+var commonEventTopics = map[common.Hash]bool{
+	crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)")): true,
+	crypto.Keccak256Hash([]byte("Approval(address,address,uint256)")): true,
+	crypto.Keccak256Hash([]byte("Deposit(address,uint256)")):          true,
+	crypto.Keccak256Hash([]byte("Withdrawal(address,uint256)")):       true,
+	crypto.Keccak256Hash([]byte("Exchange(address,uint256,address)")): true,
+	swappedTopic: true,
+}
+
+// TrafficSample represents a successful router swap picked for replay
+type TrafficSample struct {
+	TxHash      string
+	BlockNumber uint64
+	Sender      string
+	Router      string
+	Calldata    []byte
+	Value       *big.Int
+	TokenIn     string
+	TokenOut    string
+	Amount      *big.Int
+	DexKey      string // Sorted event signatures of the pools the swap went through
+	GasUsed     uint64 // Gas used by the transaction, from its receipt
+}
+
+// RunTrafficReplay samples recent successful swaps on every chain, scales their
+// calldata with the scale helper and simulates the result as the original sender
+func (m *Monitor) RunTrafficReplay(ctx context.Context) (*RunRecord, error) {
+	run := &RunRecord{
+		ID:        "traffic-" + time.Now().UTC().Format("20060102T150405Z"),
+		StartedAt: time.Now().UTC(),
+	}
+	m.resetSimulation()
+	run.RPCHealth = m.CheckRPCs(ctx)
+	run.Deployments = m.CheckDeployments(ctx)
+
+	var results []*Result
+	var failures []slack.MonitoringResult
+	for i := range m.chains {
+		chainConfig := &m.chains[i]
+		client, exists := m.ethClients[chainConfig.Name]
+		if !exists {
+			continue
+		}
+
+		reader, ok := client.(BlockReader)
+		if !ok {
+			m.logger.WithField("chain", chainConfig.Name).Warn("RPC client cannot read blocks, skipping traffic sampling")
+			continue
+		}
+		samples, err := m.SampleTraffic(ctx, chainConfig, reader)
+		if err != nil {
+			m.logger.WithError(err).WithField("chain", chainConfig.Name).Warn("Failed to sample router traffic")
+			run.Errors++
+			continue
+		}
+		m.logger.WithFields(logrus.Fields{
+			"chain":   chainConfig.Name,
+			"samples": len(samples),
+		}).Info("Sampled router traffic")
+
+		for _, sample := range samples {
+			startedAt := time.Now()
+			result, err := m.replayTraffic(ctx, chainConfig, client, sample)
+			result.ID = fmt.Sprintf("%s-%d", run.ID, len(results)+1)
+			result.DurationMs = time.Since(startedAt).Milliseconds()
+			results = append(results, result)
+
+			if err == nil {
+				continue
+			}
+			var scaleErr *CallGetScaledInputDataError
+			var propertyErr *PropertyViolationError
+			if errors.As(err, &propertyErr) {
+				result.FailureType = FailureTypeProperty
+				failures = append(failures, result)
+				m.logger.WithError(err).WithField("tx", sample.TxHash).Error("Traffic replay broke a property check")
+			} else if errors.As(err, &scaleErr) {
+				result.FailureType = FailureTypeScale
+				failures = append(failures, result)
+				m.logger.WithError(err).WithField("tx", sample.TxHash).Error("Traffic replay failed")
+			} else {
+				result.FailureType = FailureTypeInfra
+				run.Errors++
+				m.logger.WithError(err).WithField("tx", sample.TxHash).Warn("Traffic replay encountered error")
+			}
+		}
+	}
+
+	run.FinishedAt = time.Now().UTC()
+	run.Total = len(results)
+	run.Failures = len(failures)
+	run.Results = results
+	run.SizeBuckets = summarizeSizeBuckets(results)
+	run.Simulation = m.simulationUsage()
+	run.NewEncodings = m.checkEncodings(results)
+	run.GasRegressions = m.checkGas(run)
+
+	if m.history != nil {
+		if err := m.history.SaveRun(run); err != nil {
+			m.logger.WithError(err).Error("Failed to save run history")
+		}
+	}
+
+	if len(failures) > 0 {
+		m.sendAlert(failures, run.Total)
+	}
+	return run, nil
+}
+
+// SampleTraffic finds successful router swaps through their Swapped logs, newest
+// first within the configured block range, keeping at most MaxPerDex swaps per
+// dex signature
+func (m *Monitor) SampleTraffic(ctx context.Context, chainConfig *ChainConfig, client BlockReader) ([]TrafficSample, error) {
+	cfg := m.config.Traffic
+	router := common.HexToAddress(DefaultTrafficRouter)
+	if cfg.RouterAddress != "" {
+		router = common.HexToAddress(cfg.RouterAddress)
+	}
+	maxSamples, maxPerDex, blocks := cfg.MaxSamples, cfg.MaxPerDex, cfg.Blocks
+	if maxSamples <= 0 {
+		maxSamples = defaultTrafficMaxSamples
+	}
+	if maxPerDex <= 0 {
+		maxPerDex = defaultTrafficMaxPerDex
+	}
+	if blocks == 0 {
+		blocks = defaultTrafficBlocks
+	}
+
+	toBlock := cfg.ToBlock
+	if toBlock == 0 {
+		latest, err := client.BlockNumber(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get latest block: %v", err)
+		}
+		toBlock = latest
+	}
+	fromBlock := cfg.FromBlock
+	if fromBlock == 0 {
+		fromBlock = 1
+		if toBlock > blocks {
+			fromBlock = toBlock - blocks + 1
+		}
+	}
+	if fromBlock > toBlock {
+		return nil, fmt.Errorf("invalid block range %d-%d", fromBlock, toBlock)
+	}
+
+	var samples []TrafficSample
+	perDex := make(map[string]int)
+	seen := make(map[common.Hash]bool)
+	skipped := 0
+	for last := toBlock; len(samples) < maxSamples; {
+		first := fromBlock
+		if last-fromBlock >= trafficLogBlocks {
+			first = last - trafficLogBlocks + 1
+		}
+		logs, err := client.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(first),
+			ToBlock:   new(big.Int).SetUint64(last),
+			Addresses: []common.Address{router},
+			Topics:    [][]common.Hash{{swappedTopic}},
+		})
+		if err != nil {
+			return samples, fmt.Errorf("failed to filter router swaps in blocks %d-%d: %v", first, last, err)
+		}
+
+		// Logs come oldest first, the newest swaps are sampled first
+		for i := len(logs) - 1; i >= 0 && len(samples) < maxSamples; i-- {
+			if err := ctx.Err(); err != nil {
+				return samples, err
+			}
+			log := logs[i]
+			if seen[log.TxHash] {
+				continue
+			}
+			seen[log.TxHash] = true
+
+			sample, err := m.trafficSample(ctx, client, router, log)
+			if err != nil {
+				skipped++
+				m.logger.WithError(err).WithFields(logrus.Fields{
+					"chain": chainConfig.Name,
+					"tx":    log.TxHash.Hex(),
+				}).Debug("Skipped router swap")
+				continue
+			}
+			if perDex[sample.DexKey] >= maxPerDex {
+				continue
+			}
+			perDex[sample.DexKey]++
+			samples = append(samples, *sample)
+		}
+
+		if first == fromBlock {
+			break
+		}
+		last = first - 1
+	}
+
+	fields := logrus.Fields{
+		"chain":   chainConfig.Name,
+		"from":    fromBlock,
+		"to":      toBlock,
+		"swaps":   len(seen),
+		"skipped": skipped,
+	}
+	if len(samples) == 0 {
+		m.logger.WithFields(fields).Warn("No router swaps sampled")
+	} else if skipped > 0 {
+		m.logger.WithFields(fields).Warn("Skipped router swaps that could not be read")
+	}
+	return samples, nil
+}
+
+// trafficSample reads the transaction behind a router Swapped log
+func (m *Monitor) trafficSample(ctx context.Context, client BlockReader, router common.Address, log types.Log) (*TrafficSample, error) {
+	tx, _, err := client.TransactionByHash(ctx, log.TxHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %v", err)
+	}
+	if tx.To() == nil || *tx.To() != router {
+		return nil, fmt.Errorf("transaction does not call the router")
+	}
+	call, err := decodeRouterCall(m.routerABI, tx.Data())
+	if err != nil {
+		return nil, err
+	}
+
+	receipt, err := client.TransactionReceipt(ctx, log.TxHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get receipt: %v", err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return nil, fmt.Errorf("transaction reverted")
+	}
+
+	sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover sender: %v", err)
+	}
+
+	return &TrafficSample{
+		TxHash:      log.TxHash.Hex(),
+		BlockNumber: log.BlockNumber,
+		Sender:      sender.Hex(),
+		Router:      router.Hex(),
+		Calldata:    tx.Data(),
+		Value:       tx.Value(),
+		TokenIn:     strings.ToLower(call.desc.SrcToken.Hex()),
+		TokenOut:    strings.ToLower(call.desc.DstToken.Hex()),
+		Amount:      call.desc.Amount,
+		DexKey:      dexSignature(receipt.Logs, router),
+		GasUsed:     receipt.GasUsed,
+	}, nil
+}
+
+// dexSignature identifies the pools a swap went through by the events they
+// emitted, ignoring token, wrapper and router events
+func dexSignature(logs []*types.Log, router common.Address) string {
+	seen := make(map[string]bool)
+	for _, log := range logs {
+		if len(log.Topics) == 0 || log.Address == router || commonEventTopics[log.Topics[0]] {
+			continue
+		}
+		seen[log.Topics[0].Hex()[:10]] = true
+	}
+
+	signatures := make([]string, 0, len(seen))
+	for signature := range seen {
+		signatures = append(signatures, signature)
+	}
+	sort.Strings(signatures)
+	return strings.Join(signatures, ",")
+}
+
+// replayTraffic scales the calldata of a sampled swap at the parent block and
+// simulates it as the original sender. When the scaled swap fails, the original
+// calldata is simulated as well so state drift is not reported as a scale failure.
+func (m *Monitor) replayTraffic(ctx context.Context, chainConfig *ChainConfig, client ContractCaller, sample TrafficSample) (*Result, error) {
+	blockNumber := sample.BlockNumber - 1
+	tokenIn, known := m.tokens[chainConfig.Name][sample.TokenIn]
+	layout := tokenIn.Storage()
+	overrideToken := known && layout.Validate() == nil
+
+	base := Result{
+		Case:             fmt.Sprintf("traffic/%s/%s", chainConfig.Name, sample.TxHash),
+		ChainName:        chainConfig.Name,
+		TokenIn:          sample.TokenIn,
+		TokenOut:         sample.TokenOut,
+		Amount:           sample.Amount.String(),
+		Sender:           sample.Sender,
+		TxHash:           sample.TxHash,
+		InputData:        hexutil.Encode(sample.Calldata),
+		RouterAddress:    sample.Router,
+		TransactionValue: sample.Value.String(),
+		BlockNumber:      blockNumber,
+		Encoding:         decodeRouterEncoding(m.routerABI, sample.Calldata),
+		OriginalGasUsed:  sample.GasUsed,
+	}
+	fail := func(err error, errorMsg string) (*Result, error) {
+		result := base
+		result.Error = errorMsg
+		return &result, err
+	}
+
+	// The sender's real balance only covers smaller amounts when its token
+	// balance cannot be overridden
+	newAmount := scaleAmount(sample.Amount, !overrideToken && !tenderly.IsNative(sample.TokenIn))
+	base.NewAmount = newAmount.String()
+
+	scaleResult, err := m.callGetScaledInputData(ctx, client, chainConfig.ContractAddress, sample.Calldata, newAmount, new(big.Int).SetUint64(blockNumber))
+	if err != nil {
+		var helperErr *CallGetScaledInputDataError
+		if errors.As(err, &helperErr) && helperErr.Reason != "" {
+			base.RevertReason = helperErr.Reason
+			base.RevertContract = chainConfig.ContractAddress
+		}
+		return fail(err, fmt.Sprintf("Scale Failed: %v", err))
+	}
+
+	base.ReturnedData = hexutil.Encode(scaleResult.Data)
+	base.RPCEndpoint = scaleResult.Endpoint
+	m.recordHelperGas(ctx, &base, client, chainConfig.ContractAddress, sample.Calldata, newAmount)
+	if !scaleResult.IsSuccess {
+		return fail(&CallGetScaledInputDataError{ChainName: chainConfig.Name, Message: "Scale helper returned false"}, "Scale helper returned false")
+	}
+	base.PropertyViolations = m.checkProperties(ctx, PropertyInput{
+		InputData:      sample.Calldata,
+		OriginalAmount: sample.Amount,
+		NewAmount:      newAmount,
+		Output:         scaleResult.Data,
+		RouterABI:      m.routerABI,
+		Scale: func(ctx context.Context, data []byte, amount *big.Int) (*ContractCallResult, error) {
+			return m.callGetScaledInputData(ctx, client, chainConfig.ContractAddress, data, amount, new(big.Int).SetUint64(blockNumber))
+		},
+	})
+
+	scaledValue := sample.Value
+	if tenderly.IsNative(sample.TokenIn) {
+		scaledValue = newAmount
+	}
+	scaled, err := m.simulateTraffic(ctx, chainConfig, sample, base.ReturnedData, scaledValue, newAmount, overrideToken, blockNumber)
+	if err != nil {
+		return fail(err, err.Error())
+	}
+	base.Simulator = scaled.backend
+	base.ScaledTenderlyURL = scaled.URL
+	base.ScaledGasUsed = scaled.GasUsed
+	if scaled.Success && len(base.PropertyViolations) > 0 {
+		propertyErr := newPropertyViolationError(chainConfig.Name, base.PropertyViolations)
+		return fail(propertyErr, propertyErr.Error())
+	}
+	if scaled.Success {
+		result := base
+		result.IsSuccess = true
+		return &result, nil
+	}
+
+	// Tell scale failures apart from swaps that no longer execute at all
+	original, err := m.simulateTraffic(ctx, chainConfig, sample, base.InputData, sample.Value, sample.Amount, overrideToken, blockNumber)
+	if err != nil {
+		return fail(err, err.Error())
+	}
+	base.OriginalTenderlyURL = original.URL
+	base.OriginalGasUsed = original.GasUsed
+	if !original.Success {
+		return fail(fmt.Errorf("original swap %s failed: %s", sample.TxHash, original.ErrorMessage), fmt.Sprintf("Original swap failed: %s", original.ErrorMessage))
+	}
+
+	errorMsg := "Scaled swap simulation failed"
+	if scaled.ErrorMessage != "" {
+		errorMsg = fmt.Sprintf("Scaled swap failed: %s", scaled.ErrorMessage)
+	}
+	m.recordSimulationFailure(&base, scaled.SwapSimulation)
+	return fail(&CallGetScaledInputDataError{ChainName: chainConfig.Name, Message: errorMsg}, errorMsg)
+}
+
+// trafficSimulation is a swap simulation along with the backend that ran it
+type trafficSimulation struct {
+	*tenderly.SwapSimulation
+	backend string
+}
+
+// simulateTraffic simulates router calldata as the sample's sender. The token
+// balance and allowance are overridden only when the token layout is known,
+// otherwise the sender's own balance and allowance at the block are used.
+func (m *Monitor) simulateTraffic(ctx context.Context, chainConfig *ChainConfig, sample TrafficSample, data string, value, amount *big.Int, overrideToken bool, blockNumber uint64) (*trafficSimulation, error) {
+	stateObjects := map[string]interface{}{
+		sample.Sender: map[string]interface{}{"balance": "0xffffffffffffffffffffffffffff"},
+	}
+	approve := false
+	if overrideToken {
+		layout := m.tokens[chainConfig.Name][sample.TokenIn].Storage()
+		var err error
+		stateObjects, err = m.tenderlyClient.CreateStateObjectsForSwap(sample.TokenIn, sample.Router, sample.Sender, amount.String(), chainConfig.Name, layout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create state objects: %v", err)
+		}
+		approve = tenderly.NeedsApproval(sample.TokenIn, layout)
+	}
+
+	bundle := m.tenderlyClient.NewSwapBundle(
+		tenderly.GetChainNetworkID(chainConfig.ChainID),
+		sample.TokenIn,
+		sample.Sender,
+		sample.Router,
+		data,
+		value.String(),
+		stateObjects,
+		approve,
+		blockNumber,
+	)
+	simulation, backend, err := m.simulate(ctx, bundle)
+	if err != nil {
+		return nil, fmt.Errorf("%s simulation failed: %v", backend, err)
+	}
+	return &trafficSimulation{SwapSimulation: simulation, backend: backend}, nil
+}
@@ -2,69 +2,224 @@ package monitor
 
 import (
 	"scale-helper-monitor/internal/clients/kyberswap"
+	"scale-helper-monitor/internal/clients/tenderly"
+	"scale-helper-monitor/internal/storage"
 )
 
 // Config represents the monitoring configuration
 type Config struct {
-	Interval string `mapstructure:"interval"`
-	Timeout  string `mapstructure:"timeout"`
+	Interval          string           `mapstructure:"interval"`
+	Timeout           string           `mapstructure:"timeout"`
+	HistoryFile       string           `mapstructure:"history_file"`
+	MaxHistoryRuns    int              `mapstructure:"max_history_runs"`
+	ErrorABIFiles     []string         `mapstructure:"error_abi_files"`    // Extra ABIs whose custom errors decode reverts
+	FallbackSimulator string           `mapstructure:"fallback_simulator"` // Simulator used once Tenderly quota runs out, "rpc" or empty
+	Traffic           TrafficConfig    `mapstructure:"traffic"`            // Sampling of recent router swaps for the traffic command
+	Encodings         EncodingConfig   `mapstructure:"encodings"`          // Router encodings the scale helper supports
+	CorpusFile        string           `mapstructure:"corpus_file"`        // Golden getScaledInputData inputs and outputs, DefaultCorpusFile when empty
+	PropertyChecks    []string         `mapstructure:"property_checks"`    // Property checks run on every helper result: identity, composition, invariants
+	EdgeScenarios     EdgeConfig       `mapstructure:"edge_scenarios"`     // Dust, zero and extreme amounts run on every test case
+	Gas               GasConfig        `mapstructure:"gas"`                // Gas regression thresholds
+	Deployments       DeploymentConfig `mapstructure:"deployments"`        // Scale helper bytecode known to be good
+	RPC               RPCConfig        `mapstructure:"rpc"`                // RPC endpoint health checks
+	DryRun            bool             `mapstructure:"-"`                  // Run everything but skip notifications
 }
 
 // ChainConfig represents blockchain configuration
 type ChainConfig struct {
-	Name            string `mapstructure:"name"`
-	ChainID         int    `mapstructure:"chain_id"`
-	RPCURL          string `mapstructure:"rpc_url"`
-	ContractAddress string `mapstructure:"contract_address"`
+	Name            string            `mapstructure:"name"`
+	ChainID         int               `mapstructure:"chain_id"`
+	RPCURLs         []string          `mapstructure:"rpc_urls"` // Tried in order, the next one takes over when an endpoint fails
+	ContractAddress string            `mapstructure:"contract_address"`
+	Helpers         map[string]string `mapstructure:"helpers"` // Other helper deployments compared with ContractAddress, label -> address
 }
 
 // TokenInfo represents token information including slot, symbol, amount, and decimal
 type TokenInfo struct {
-	Slot     string `json:"slot"`
-	Symbol   string `json:"symbol"`
-	Decimals string `json:"decimals"`
+	Slot          string `json:"slot,omitempty"`           // Precomputed balance slot of 0xdeAD…dEAd, superseded by balance_slot
+	Layout        string `json:"layout,omitempty"`         // solidity (default), vyper or erc7201
+	Namespace     string `json:"namespace,omitempty"`      // ERC-7201 namespace id, for the erc7201 layout
+	BalanceSlot   string `json:"balance_slot,omitempty"`   // Base slot of the balance mapping
+	AllowanceSlot string `json:"allowance_slot,omitempty"` // Base slot of the allowance mapping, approved in the bundle when empty
+	Symbol        string `json:"symbol"`
+	Decimals      string `json:"decimals"`
+}
+
+// Storage returns the storage layout used to override balances and allowances
+func (t TokenInfo) Storage() storage.TokenLayout {
+	return storage.TokenLayout{
+		Layout:        t.Layout,
+		Namespace:     t.Namespace,
+		BalanceSlot:   t.BalanceSlot,
+		AllowanceSlot: t.AllowanceSlot,
+		Slot:          t.Slot,
+	}
 }
 
 type TestCase struct {
-	ChainName       string   `mapstructure:"chain_name"`
-	TokenIn         string   `mapstructure:"token_in"`
-	TokenOut        string   `mapstructure:"token_out"`
-	Amount          string   `mapstructure:"amount"`
-	IncludedSources []string `mapstructure:"included_sources"`
+	Name            string       `mapstructure:"name"`
+	Tags            []string     `mapstructure:"tags"`
+	ChainName       string       `mapstructure:"chain_name"`
+	TokenIn         string       `mapstructure:"token_in"`
+	TokenOut        string       `mapstructure:"token_out"`
+	Amount          string       `mapstructure:"amount"`
+	Amounts         []string     `mapstructure:"amounts"`
+	AmountRange     *AmountRange `mapstructure:"amount_range"`
+	IncludedSources []string     `mapstructure:"included_sources"`
+	Sender          string       `mapstructure:"sender"`       // Swap sender, defaults to DefaultSender
+	Recipient       string       `mapstructure:"recipient"`    // Defaults to the sender
+	SlippageBps     *int         `mapstructure:"slippage_bps"` // Unset keeps the permissive default
+	ExtraFee        *ExtraFee    `mapstructure:"extra_fee"`
+	Permit          string       `mapstructure:"permit"`       // Encoded permit for route/build, must be valid for the sender
+	Environments    []string     `mapstructure:"environments"` // Aggregator environments to run against, defaults to production only
+	SizeBucket      string       `mapstructure:"-"`            // Set when the test case is expanded into sub-cases
+	Environment     string       `mapstructure:"-"`            // Set when the test case is expanded per environment
+
+	group    int         // Sub-cases of the same test case and size share a group
+	siblings *siblingRun // Shared with the other environments of the group during a run
+}
+
+// ExtraFee represents the partner fee charged on a test case route
+type ExtraFee struct {
+	FeeReceiver string `mapstructure:"fee_receiver"`
+	FeeBps      int    `mapstructure:"fee_bps"`
+	ChargeFeeBy string `mapstructure:"charge_fee_by"` // currency_in or currency_out
+}
+
+// DefaultSender is the swap sender of test cases without a sender
+const DefaultSender = storage.LegacySlotHolder
+
+// sender returns the swap sender of the test case
+func (tc TestCase) sender() string {
+	if tc.Sender == "" {
+		return DefaultSender
+	}
+	return tc.Sender
+}
+
+// routeParams returns the integrator settings used to build the route
+func (tc TestCase) routeParams() kyberswap.RouteParams {
+	params := kyberswap.RouteParams{
+		Sender:      tc.sender(),
+		Recipient:   tc.Recipient,
+		SlippageBps: tc.SlippageBps,
+		Permit:      tc.Permit,
+	}
+	if tc.ExtraFee != nil {
+		params.ExtraFee = &kyberswap.FeeParams{
+			FeeReceiver: tc.ExtraFee.FeeReceiver,
+			FeeBps:      tc.ExtraFee.FeeBps,
+			ChargeFeeBy: tc.ExtraFee.ChargeFeeBy,
+		}
+	}
+	return params
 }
 
+// AmountRange represents a log-scale sweep of swap sizes between Min and Max
+type AmountRange struct {
+	Min   string `mapstructure:"min"`
+	Max   string `mapstructure:"max"`
+	Steps int    `mapstructure:"steps"`
+}
+
+// Failure types recorded on a Result
+const (
+	FailureTypeScale    = "scale"    // Scale helper or scaled simulation failure, triggers alerts
+	FailureTypeInfra    = "infra"    // API, RPC or simulation infrastructure error
+	FailureTypeProperty = "property" // Helper output executes but breaks a property check, triggers alerts
+)
+
 // Result represents the result of a monitoring check
 type Result struct {
+	ID                  string                      `json:"id,omitempty"`
+	Case                string                      `json:"case,omitempty"`
 	ChainName           string                      `json:"chain_name"`
 	TokenIn             string                      `json:"token_in"`
 	TokenOut            string                      `json:"token_out"`
 	Amount              string                      `json:"amount"`
+	Sender              string                      `json:"sender,omitempty"`
+	SizeBucket          string                      `json:"size_bucket,omitempty"`
+	Environment         string                      `json:"environment,omitempty"` // Aggregator environment, empty when the test case has none
+	TxHash              string                      `json:"tx_hash,omitempty"`     // Router swap the calldata was sampled from, traffic replay only
+	Scenario            string                      `json:"scenario,omitempty"`    // Edge scenario, empty for the randomly scaled amount
 	IsSuccess           bool                        `json:"is_success"`
 	ReturnedData        string                      `json:"returned_data"`
 	InputData           string                      `json:"input_data"`
+	Encoding            *RouterEncoding             `json:"encoding,omitempty"` // Router selector, executor and executor functions of InputData
+	RouterAddress       string                      `json:"router_address,omitempty"`
+	TransactionValue    string                      `json:"transaction_value,omitempty"`
+	BlockNumber         uint64                      `json:"block_number,omitempty"`
+	RPCEndpoint         string                      `json:"rpc_endpoint,omitempty"` // Host of the RPC endpoint that served the helper call
 	Route               [][]kyberswap.KyberSwapSwap `json:"route"`
 	NewAmount           string                      `json:"new_amount"`
 	Error               string                      `json:"error,omitempty"`
+	RevertReason        string                      `json:"revert_reason,omitempty"`   // Decoded revert of the failing call
+	RevertContract      string                      `json:"revert_contract,omitempty"` // Contract that reverted, when known
+	FailureTrace        *tenderly.FailureSummary    `json:"failure_trace,omitempty"`   // Deepest reverting frame and asset changes
+	FailureType         string                      `json:"failure_type,omitempty"`
+	DurationMs          int64                       `json:"duration_ms,omitempty"`
 	OriginalTenderlyURL string                      `json:"original_tenderly_url,omitempty"`
 	ScaledTenderlyURL   string                      `json:"scaled_tenderly_url,omitempty"`
+	Simulator           string                      `json:"simulator,omitempty"`     // Backend of the last simulation, "tenderly" or the fallback
+	VersionDiffs        []VersionDiff               `json:"version_diffs,omitempty"` // Other helper deployments on the same input
+	PropertyViolations  []PropertyViolation         `json:"property_violations,omitempty"`
+	OriginalGasUsed     uint64                      `json:"original_gas_used,omitempty"` // Simulated original swap, or the sampled transaction's receipt
+	ScaledGasUsed       uint64                      `json:"scaled_gas_used,omitempty"`
+	HelperGas           uint64                      `json:"helper_gas,omitempty"` // eth_estimateGas of the getScaledInputData call
+
+	// Tenderly bundle requests, attached to Slack threads in bot-token mode
+	OriginalSimulationPayload string `json:"original_simulation_payload,omitempty"`
+	ScaledSimulationPayload   string `json:"scaled_simulation_payload,omitempty"`
 }
 
 // ContractCallResult represents the result of calling getScaledInputData
 type ContractCallResult struct {
 	IsSuccess bool
 	Data      []byte
+	Endpoint  string // RPC endpoint that served the call
 }
 
 // Implement the slack.MonitoringResult interface
+func (r *Result) GetID() string                         { return r.ID }
+func (r *Result) GetCase() string                       { return r.Case }
 func (r *Result) GetChainName() string                  { return r.ChainName }
 func (r *Result) GetTokenIn() string                    { return r.TokenIn }
 func (r *Result) GetTokenOut() string                   { return r.TokenOut }
 func (r *Result) GetAmount() string                     { return r.Amount }
+func (r *Result) GetSizeBucket() string                 { return r.SizeBucket }
+func (r *Result) GetEnvironment() string                { return r.Environment }
+func (r *Result) GetTxHash() string                     { return r.TxHash }
+func (r *Result) GetScenario() string                   { return r.Scenario }
 func (r *Result) GetIsSuccess() bool                    { return r.IsSuccess }
 func (r *Result) GetError() string                      { return r.Error }
+func (r *Result) GetRevertReason() string               { return r.RevertReason }
+func (r *Result) GetRevertContract() string             { return r.RevertContract }
 func (r *Result) GetInputData() string                  { return r.InputData }
 func (r *Result) GetReturnedData() string               { return r.ReturnedData }
 func (r *Result) GetRoute() [][]kyberswap.KyberSwapSwap { return r.Route }
 func (r *Result) GetNewAmount() string                  { return r.NewAmount }
+func (r *Result) GetBlockNumber() uint64                { return r.BlockNumber }
 func (r *Result) GetOriginalTenderlyURL() string        { return r.OriginalTenderlyURL }
 func (r *Result) GetScaledTenderlyURL() string          { return r.ScaledTenderlyURL }
+func (r *Result) GetOriginalSimulationPayload() string  { return r.OriginalSimulationPayload }
+func (r *Result) GetScaledSimulationPayload() string    { return r.ScaledSimulationPayload }
+
+// GetFailingFrame names the contract and function where the failed simulation reverted
+func (r *Result) GetFailingFrame() string {
+	if r.FailureTrace == nil {
+		return ""
+	}
+	return r.FailureTrace.Location()
+}
+
+// GetTransfers lists the asset changes of the failed simulation
+func (r *Result) GetTransfers() []string {
+	if r.FailureTrace == nil {
+		return nil
+	}
+	transfers := make([]string, 0, len(r.FailureTrace.Transfers))
+	for _, transfer := range r.FailureTrace.Transfers {
+		transfers = append(transfers, transfer.String())
+	}
+	return transfers
+}
@@ -0,0 +1,109 @@
+package monitor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"scale-helper-monitor/internal/clients/kyberswap"
+	"scale-helper-monitor/internal/clients/tenderly"
+)
+
+// ValidateTestCases checks test cases against the token list, chain configuration
+// and available liquidity sources. It returns one error per problem found.
+func ValidateTestCases(
+	testCases []TestCase,
+	tokens map[string]map[string]TokenInfo,
+	chains []ChainConfig,
+	liquiditySources map[string][]string,
+) []error {
+	var problems []error
+
+	chainsByName := make(map[string]ChainConfig)
+	for _, chain := range chains {
+		chainsByName[chain.Name] = chain
+	}
+
+	for _, testCase := range testCases {
+		key := TestCaseKey(testCase, tokens)
+		report := func(format string, args ...interface{}) {
+			problems = append(problems, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+		}
+
+		chain, exists := chainsByName[testCase.ChainName]
+		if !exists {
+			report("chain %s is not configured", testCase.ChainName)
+		} else {
+			if len(chain.RPCURLs) == 0 {
+				report("no RPC URL configured for chain %s", chain.Name)
+			}
+			if chain.ContractAddress == "" {
+				report("no scale helper address configured for chain %s", chain.Name)
+			}
+			for label, address := range chain.Helpers {
+				if label == CurrentHelper || !common.IsHexAddress(address) {
+					report("invalid scale helper %s %q for chain %s", label, address, chain.Name)
+				}
+			}
+		}
+
+		for _, token := range []string{testCase.TokenIn, testCase.TokenOut} {
+			info, exists := tokens[testCase.ChainName][token]
+			if !exists {
+				report("token %s is missing from tokens.json", token)
+				continue
+			}
+			if _, err := strconv.ParseInt(info.Decimals, 10, 64); err != nil {
+				report("token %s has invalid decimals %q", token, info.Decimals)
+			}
+		}
+
+		if info, exists := tokens[testCase.ChainName][testCase.TokenIn]; exists && !tenderly.IsNative(testCase.TokenIn) {
+			if err := info.Storage().Validate(); err != nil {
+				report("token in %s has an invalid storage layout: %v", testCase.TokenIn, err)
+			} else if _, err := info.Storage().BalanceOf(testCase.sender()); err != nil {
+				report("token in %s: %v", testCase.TokenIn, err)
+			} else if testCase.Sender != "" && !strings.EqualFold(testCase.Sender, DefaultSender) && info.Storage().AllowanceSlot == "" {
+				report("token in %s has no allowance_slot, required for sender %s", testCase.TokenIn, testCase.Sender)
+			}
+		}
+
+		for _, address := range []string{testCase.Sender, testCase.Recipient} {
+			if address != "" && !common.IsHexAddress(address) {
+				report("invalid address %s", address)
+			}
+		}
+		if slippage := testCase.SlippageBps; slippage != nil && (*slippage < 0 || *slippage > 10000) {
+			report("slippage_bps %d is outside 0-10000", *slippage)
+		}
+		if fee := testCase.ExtraFee; fee != nil {
+			if !common.IsHexAddress(fee.FeeReceiver) {
+				report("extra_fee.fee_receiver %q is not an address", fee.FeeReceiver)
+			}
+			if fee.FeeBps <= 0 || fee.FeeBps > 10000 {
+				report("extra_fee.fee_bps %d is outside 1-10000", fee.FeeBps)
+			}
+			if fee.ChargeFeeBy != kyberswap.ChargeFeeByCurrencyIn && fee.ChargeFeeBy != kyberswap.ChargeFeeByCurrencyOut {
+				report("extra_fee.charge_fee_by must be %s or %s", kyberswap.ChargeFeeByCurrencyIn, kyberswap.ChargeFeeByCurrencyOut)
+			}
+		}
+
+		if _, err := testCaseAmounts(testCase); err != nil {
+			report("%v", err)
+		}
+
+		available := liquiditySources[testCase.ChainName]
+		for _, source := range testCase.IncludedSources {
+			if source == "random" || len(available) == 0 {
+				continue
+			}
+			if !containsFold(available, source) {
+				report("included source %s is not available on %s", source, testCase.ChainName)
+			}
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,176 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/sirupsen/logrus"
+
+	"scale-helper-monitor/internal/clients/tenderly"
+)
+
+// CurrentHelper labels ChainConfig.ContractAddress in version diffs
+const CurrentHelper = "current"
+
+// Version diff verdicts, from the point of view of the other deployment
+const (
+	VerdictSame      = "same"      // Identical isSuccess and calldata, or the same revert
+	VerdictDifferent = "different" // Different calldata with the same outcome
+	VerdictBetter    = "better"    // Passes where the current helper fails
+	VerdictWorse     = "worse"     // Fails where the current helper passes
+	VerdictUnknown   = "unknown"   // The current helper or this deployment hit an infrastructure error
+)
+
+// VersionDiff compares another helper deployment with the current one on the same input
+type VersionDiff struct {
+	Label             string `json:"label"`
+	Address           string `json:"address"`
+	IsSuccess         bool   `json:"is_success"`
+	Error             string `json:"error,omitempty"`
+	ReturnedData      string `json:"returned_data,omitempty"`
+	DataMatches       bool   `json:"data_matches"`
+	Simulated         bool   `json:"simulated"` // Only calldata that differs from the current helper's is simulated
+	SimulationSuccess bool   `json:"simulation_success,omitempty"`
+	SimulationError   string `json:"simulation_error,omitempty"`
+	RevertReason      string `json:"revert_reason,omitempty"`
+	TenderlyURL       string `json:"tenderly_url,omitempty"`
+	Verdict           string `json:"verdict"`
+
+	passes     bool // Helper succeeded and its calldata swaps, set before the verdict
+	infra      bool // Helper call or simulation hit an infrastructure error
+	sameRevert bool // Helper reverted with the current helper's revert data
+}
+
+// scaledSwap holds what is needed to simulate calldata returned by a helper
+type scaledSwap struct {
+	chain     *ChainConfig
+	tokenIn   string
+	token     TokenInfo
+	router    string
+	from      string
+	value     string
+	amount    string
+	block     uint64
+	currentOK bool   // Current helper call returned isSuccess
+	current   []byte // Calldata returned by the current helper
+
+	currentRevert *CallGetScaledInputDataError // Current helper revert, nil when it did not revert
+}
+
+// compareHelpers calls every other helper deployment of the chain with the same
+// input and simulates the calldata that differs from the current helper's
+func (m *Monitor) compareHelpers(ctx context.Context, client ContractCaller, swap scaledSwap, inputData []byte, newAmount *big.Int) []VersionDiff {
+	if len(swap.chain.Helpers) == 0 {
+		return nil
+	}
+
+	labels := make([]string, 0, len(swap.chain.Helpers))
+	for label := range swap.chain.Helpers {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	diffs := make([]VersionDiff, 0, len(labels))
+	for _, label := range labels {
+		diff := VersionDiff{Label: label, Address: swap.chain.Helpers[label]}
+
+		result, err := m.callGetScaledInputData(ctx, client, diff.Address, inputData, newAmount, new(big.Int).SetUint64(swap.block))
+		if err != nil {
+			var helperErr *CallGetScaledInputDataError
+			diff.infra = !errors.As(err, &helperErr)
+			diff.Error = err.Error()
+			if helperErr != nil {
+				diff.RevertReason = helperErr.Reason
+				diff.sameRevert = swap.currentRevert != nil &&
+					helperErr.Data == swap.currentRevert.Data && helperErr.Reason == swap.currentRevert.Reason
+			}
+			diffs = append(diffs, diff)
+			continue
+		}
+
+		diff.IsSuccess = result.IsSuccess
+		diff.ReturnedData = hexutil.Encode(result.Data)
+		diff.DataMatches = result.IsSuccess == swap.currentOK && bytes.Equal(result.Data, swap.current)
+		if result.IsSuccess && !diff.DataMatches {
+			m.simulateHelperOutput(ctx, swap, &diff)
+		}
+
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
+
+// simulateHelperOutput simulates the calldata another helper deployment returned
+func (m *Monitor) simulateHelperOutput(ctx context.Context, swap scaledSwap, diff *VersionDiff) {
+	diff.Simulated = true
+
+	stateObjects, err := m.tenderlyClient.CreateStateObjectsForSwap(swap.tokenIn, swap.router, swap.from, swap.amount, swap.chain.Name, swap.token.Storage())
+	if err != nil {
+		diff.infra = true
+		diff.SimulationError = fmt.Sprintf("Failed to create state objects: %v", err)
+		return
+	}
+
+	bundle := m.tenderlyClient.NewSwapBundle(
+		tenderly.GetChainNetworkID(swap.chain.ChainID),
+		swap.tokenIn,
+		swap.from,
+		swap.router,
+		diff.ReturnedData,
+		swap.value,
+		stateObjects,
+		tenderly.NeedsApproval(swap.tokenIn, swap.token.Storage()),
+		swap.block,
+	)
+	simulation, simulator, err := m.simulate(ctx, bundle)
+	if err != nil {
+		diff.infra = true
+		diff.SimulationError = fmt.Sprintf("%s simulation failed: %v", simulator, err)
+		return
+	}
+
+	diff.SimulationSuccess = simulation.Success
+	diff.TenderlyURL = simulation.URL
+	diff.passes = simulation.Success
+	if !simulation.Success {
+		diff.SimulationError = simulation.ErrorMessage
+		diff.RevertReason = m.revertDecoder.DecodeHex(simulation.RevertData).String()
+	}
+}
+
+// finalizeVersionDiffs sets the verdict of each version diff once the outcome of
+// the current helper is known. err is the error MonitorChain returned.
+func finalizeVersionDiffs(result *Result, err error, logger *logrus.Logger) {
+	var scaleErr *CallGetScaledInputDataError
+	currentInfra := err != nil && !errors.As(err, &scaleErr)
+	currentPasses := err == nil
+
+	for i := range result.VersionDiffs {
+		diff := &result.VersionDiffs[i]
+		switch {
+		case diff.DataMatches, diff.sameRevert:
+			diff.Verdict = VerdictSame
+			continue
+		case diff.infra || currentInfra:
+			diff.Verdict = VerdictUnknown
+		case diff.passes && !currentPasses:
+			diff.Verdict = VerdictBetter
+		case !diff.passes && currentPasses:
+			diff.Verdict = VerdictWorse
+		default:
+			diff.Verdict = VerdictDifferent
+		}
+
+		logger.WithFields(logrus.Fields{
+			"case":    result.Case,
+			"helper":  diff.Label,
+			"address": diff.Address,
+			"verdict": diff.Verdict,
+		}).Warn("Scale helper versions diverge")
+	}
+}
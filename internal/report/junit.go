@@ -0,0 +1,173 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+
+	"scale-helper-monitor/internal/monitor"
+)
+
+// junitTestSuites represents the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite groups the test cases of one chain
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase represents one scale test
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+}
+
+// junitProblem represents a failure or error element
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// WriteJUnit writes a JUnit XML report with one testcase per scale test
+func WriteJUnit(path string, run *monitor.RunRecord) error {
+	suitesByChain := make(map[string]*junitTestSuite)
+	durations := make(map[string]int64)
+
+	for _, result := range run.Results {
+		suite, exists := suitesByChain[result.ChainName]
+		if !exists {
+			suite = &junitTestSuite{
+				Name:      result.ChainName,
+				Timestamp: run.StartedAt.Format("2006-01-02T15:04:05"),
+			}
+			suitesByChain[result.ChainName] = suite
+		}
+
+		testCase := junitTestCase{
+			Name:      testCaseName(result),
+			ClassName: "scale-helper." + result.ChainName,
+			Time:      formatSeconds(result.DurationMs),
+		}
+
+		switch result.FailureType {
+		case monitor.FailureTypeScale:
+			testCase.Failure = &junitProblem{Message: result.Error, Type: monitor.FailureTypeScale, Body: resultDetails(result)}
+			suite.Failures++
+		case monitor.FailureTypeProperty:
+			testCase.Failure = &junitProblem{Message: result.Error, Type: monitor.FailureTypeProperty, Body: resultDetails(result)}
+			suite.Failures++
+		case monitor.FailureTypeInfra:
+			testCase.Error = &junitProblem{Message: result.Error, Type: monitor.FailureTypeInfra, Body: resultDetails(result)}
+			suite.Errors++
+		}
+
+		suite.Tests++
+		suite.Cases = append(suite.Cases, testCase)
+		durations[result.ChainName] += result.DurationMs
+	}
+
+	report := junitTestSuites{
+		Name:     "scale-helper-monitor",
+		Tests:    run.Total,
+		Failures: run.Failures,
+		Errors:   run.Errors,
+	}
+
+	chains := make([]string, 0, len(suitesByChain))
+	for chain := range suitesByChain {
+		chains = append(chains, chain)
+	}
+	sort.Strings(chains)
+	for _, chain := range chains {
+		suite := suitesByChain[chain]
+		suite.Time = formatSeconds(durations[chain])
+		report.Suites = append(report.Suites, *suite)
+	}
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JUnit report: %v", err)
+	}
+
+	return writeFile(path, append([]byte(xml.Header), data...))
+}
+
+// testCaseName names a result after its test case, size bucket and edge scenario
+func testCaseName(result *monitor.Result) string {
+	name := result.Case
+	if name == "" {
+		name = fmt.Sprintf("%s %s-%s", result.ChainName, result.TokenIn, result.TokenOut)
+	}
+	if result.SizeBucket != "" {
+		name = fmt.Sprintf("%s [%s]", name, result.SizeBucket)
+	}
+	if result.Scenario != "" {
+		name = fmt.Sprintf("%s (edge %s)", name, result.Scenario)
+	}
+	return name
+}
+
+// resultDetails lists the fields needed to investigate a failed result
+func resultDetails(result *monitor.Result) string {
+	var lines []string
+	add := func(label, value string) {
+		if value != "" {
+			lines = append(lines, fmt.Sprintf("%s: %s", label, value))
+		}
+	}
+
+	add("Result ID", result.ID)
+	add("Token in", result.TokenIn)
+	add("Token out", result.TokenOut)
+	add("Amount", result.Amount)
+	add("New amount", result.NewAmount)
+	if result.BlockNumber > 0 {
+		add("Block", fmt.Sprintf("%d", result.BlockNumber))
+	}
+	add("Router", result.RouterAddress)
+	add("Sampled tx", result.TxHash)
+	if result.OriginalGasUsed > 0 || result.ScaledGasUsed > 0 {
+		add("Swap gas", fmt.Sprintf("original %d, scaled %d", result.OriginalGasUsed, result.ScaledGasUsed))
+	}
+	if result.HelperGas > 0 {
+		add("Helper gas", fmt.Sprintf("%d", result.HelperGas))
+	}
+	add("Original simulation", result.OriginalTenderlyURL)
+	add("Scaled simulation", result.ScaledTenderlyURL)
+	add("Error", result.Error)
+	add("Revert reason", result.RevertReason)
+	add("Reverting contract", result.RevertContract)
+	for _, violation := range result.PropertyViolations {
+		add("Property "+violation.Property, strings.TrimSpace(violation.Message+" "+violation.Diff))
+	}
+	if result.FailureTrace != nil {
+		add("Reverted in", result.FailureTrace.Location())
+		for _, transfer := range result.FailureTrace.Transfers {
+			add("Transfer", transfer.String())
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func formatSeconds(durationMs int64) string {
+	return fmt.Sprintf("%.3f", float64(durationMs)/1000)
+}
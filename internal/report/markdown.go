@@ -0,0 +1,307 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"scale-helper-monitor/internal/monitor"
+)
+
+// WriteMarkdown appends a Markdown summary of the run, suitable for $GITHUB_STEP_SUMMARY
+func WriteMarkdown(path string, run *monitor.RunRecord) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create report directory: %v", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open Markdown report %s: %v", path, err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(renderMarkdown(run)); err != nil {
+		return fmt.Errorf("failed to write Markdown report %s: %v", path, err)
+	}
+
+	return nil
+}
+
+// renderMarkdown renders the run summary, size buckets and failures
+func renderMarkdown(run *monitor.RunRecord) string {
+	var b strings.Builder
+
+	status := "✅ All tests passed"
+	if run.Failures > 0 {
+		status = fmt.Sprintf("🚨 %d scale failures", run.Failures)
+	} else if run.Errors > 0 {
+		status = fmt.Sprintf("⚠️ %d infrastructure errors", run.Errors)
+	}
+
+	passed := run.Total - run.Failures - run.Errors
+	fmt.Fprintf(&b, "## Scale Helper Monitor - %s\n\n", status)
+	fmt.Fprintf(&b, "Run `%s`, %s to %s UTC\n\n", run.ID, run.StartedAt.Format("2006-01-02 15:04:05"), run.FinishedAt.Format("15:04:05"))
+	b.WriteString("| Total | Passed | Scale failures | Infra errors |\n")
+	b.WriteString("|---|---|---|---|\n")
+	fmt.Fprintf(&b, "| %d | %d | %d | %d |\n\n", run.Total, passed, run.Failures, run.Errors)
+
+	if usage := run.Simulation; usage != nil {
+		if usage.FallbackAt != nil {
+			fmt.Fprintf(&b, "> ⚠️ **Tenderly quota exhausted at %s UTC, the rest of the run was simulated with `%s`.** Reason: %s\n\n",
+				usage.FallbackAt.Format("15:04:05"), usage.Backend, escapeCell(usage.FallbackReason))
+		}
+		limit := "unlimited"
+		if usage.DailyLimit > 0 {
+			limit = fmt.Sprintf("%d", usage.DailyLimit)
+		}
+		fmt.Fprintf(&b, "Tenderly simulations: %d this run, %d today (daily limit %s)\n\n", usage.TenderlySimulations, usage.TenderlyToday, limit)
+	}
+
+	var failingBuckets []monitor.SizeBucketSummary
+	for _, bucket := range run.SizeBuckets {
+		if bucket.Failures > 0 || bucket.Errors > 0 {
+			failingBuckets = append(failingBuckets, bucket)
+		}
+	}
+	if len(failingBuckets) > 0 {
+		b.WriteString("### By size bucket\n\n")
+		b.WriteString("| Chain | Size | Total | Scale failures | Infra errors |\n")
+		b.WriteString("|---|---|---|---|---|\n")
+		for _, bucket := range failingBuckets {
+			fmt.Fprintf(&b, "| %s | %s | %d | %d | %d |\n", bucket.ChainName, bucket.SizeBucket, bucket.Total, bucket.Failures, bucket.Errors)
+		}
+		b.WriteString("\n")
+	}
+
+	writeResultTable(&b, "Scale failures", run.Results, monitor.FailureTypeScale)
+	writeResultTable(&b, "Property violations", run.Results, monitor.FailureTypeProperty)
+	writeResultTable(&b, "Infrastructure errors", run.Results, monitor.FailureTypeInfra)
+	writeVersionDiffs(&b, run.Results)
+	writeEnvironmentDiffs(&b, run.EnvironmentDiffs)
+	writeNewEncodings(&b, run.NewEncodings)
+	writeGas(&b, run.HelperGas, run.GasRegressions)
+	writeDeployments(&b, run.Deployments)
+	writeRPCHealth(&b, run.RPCHealth)
+
+	return b.String()
+}
+
+// writeResultTable lists the results of one failure type
+func writeResultTable(b *strings.Builder, title string, results []*monitor.Result, failureType string) {
+	var rows []*monitor.Result
+	for _, result := range results {
+		if result.FailureType == failureType {
+			rows = append(rows, result)
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "### %s\n\n", title)
+	b.WriteString("| Result | Case | Size | Block | Error | Simulations |\n")
+	b.WriteString("|---|---|---|---|---|---|\n")
+	for _, result := range rows {
+		var links []string
+		if result.OriginalTenderlyURL != "" {
+			links = append(links, fmt.Sprintf("[original](%s)", result.OriginalTenderlyURL))
+		}
+		if result.ScaledTenderlyURL != "" {
+			links = append(links, fmt.Sprintf("[scaled](%s)", result.ScaledTenderlyURL))
+		}
+		errorCell := result.Error
+		if result.RevertReason != "" {
+			errorCell = fmt.Sprintf("%s (reason: %s)", errorCell, result.RevertReason)
+		}
+		if result.FailureTrace != nil {
+			errorCell = fmt.Sprintf("%s in %s", errorCell, result.FailureTrace.Location())
+		}
+		caseName := result.Case
+		if result.Environment != "" {
+			caseName = fmt.Sprintf("%s [%s]", caseName, result.Environment)
+		}
+		if result.Scenario != "" {
+			caseName = fmt.Sprintf("%s (edge %s)", caseName, result.Scenario)
+		}
+		fmt.Fprintf(b, "| `%s` | %s | %s | %d | %s | %s |\n",
+			result.ID, caseName, result.SizeBucket, result.BlockNumber, escapeCell(errorCell), strings.Join(links, " "))
+	}
+	b.WriteString("\n")
+}
+
+// writeVersionDiffs lists the results where another helper deployment diverged from the current one
+func writeVersionDiffs(b *strings.Builder, results []*monitor.Result) {
+	var rows []string
+	compared := 0
+	for _, result := range results {
+		for _, diff := range result.VersionDiffs {
+			compared++
+			if diff.Verdict == monitor.VerdictSame {
+				continue
+			}
+
+			outcome := fmt.Sprintf("isSuccess=%t", diff.IsSuccess)
+			if diff.Error != "" {
+				outcome = diff.Error
+			} else if diff.Simulated {
+				outcome = "simulation passed"
+				if !diff.SimulationSuccess {
+					outcome = fmt.Sprintf("simulation failed: %s", diff.SimulationError)
+					if diff.RevertReason != "" {
+						outcome = fmt.Sprintf("%s (reason: %s)", outcome, diff.RevertReason)
+					}
+				}
+			}
+			current := "passed"
+			if result.Error != "" {
+				current = result.Error
+			}
+			simulation := ""
+			if diff.TenderlyURL != "" {
+				simulation = fmt.Sprintf("[simulation](%s)", diff.TenderlyURL)
+			}
+
+			rows = append(rows, fmt.Sprintf("| `%s` | %s | %s | **%s** | %s | %s | %s |\n",
+				result.ID, result.Case, diff.Label, diff.Verdict, escapeCell(current), escapeCell(outcome), simulation))
+		}
+	}
+	if compared == 0 {
+		return
+	}
+
+	b.WriteString("### Version diff\n\n")
+	if len(rows) == 0 {
+		fmt.Fprintf(b, "All %d comparisons matched the current scale helper.\n\n", compared)
+		return
+	}
+	fmt.Fprintf(b, "%d of %d comparisons diverged from the current scale helper.\n\n", len(rows), compared)
+	b.WriteString("| Result | Case | Helper | Verdict | Current | Helper outcome | Simulation |\n")
+	b.WriteString("|---|---|---|---|---|---|---|\n")
+	for _, row := range rows {
+		b.WriteString(row)
+	}
+	b.WriteString("\n")
+}
+
+// writeEnvironmentDiffs lists the test cases that pass in one aggregator environment and fail in another
+func writeEnvironmentDiffs(b *strings.Builder, diffs []monitor.EnvironmentDiff) {
+	if len(diffs) == 0 {
+		return
+	}
+
+	b.WriteString("### Environment diff\n\n")
+	b.WriteString("| Case | Size | Outcomes |\n")
+	b.WriteString("|---|---|---|\n")
+	for _, diff := range diffs {
+		environments := make([]string, 0, len(diff.Outcomes))
+		for environment := range diff.Outcomes {
+			environments = append(environments, environment)
+		}
+		sort.Strings(environments)
+
+		outcomes := make([]string, 0, len(environments))
+		for _, environment := range environments {
+			outcomes = append(outcomes, fmt.Sprintf("%s: **%s** (`%s`)", environment, diff.Outcomes[environment], diff.ResultIDs[environment]))
+		}
+		caseName := diff.Case
+		if diff.Scenario != "" {
+			caseName = fmt.Sprintf("%s (edge %s)", caseName, diff.Scenario)
+		}
+		fmt.Fprintf(b, "| %s | %s | %s |\n", caseName, diff.SizeBucket, strings.Join(outcomes, ", "))
+	}
+	b.WriteString("\n")
+}
+
+// writeNewEncodings lists the router encodings seen for the first time
+func writeNewEncodings(b *strings.Builder, drifts []monitor.EncodingDrift) {
+	if len(drifts) == 0 {
+		return
+	}
+
+	b.WriteString("### New router encodings\n\n")
+	b.WriteString("| Kind | Value | Chain | Case | Result |\n")
+	b.WriteString("|---|---|---|---|---|\n")
+	for _, drift := range drifts {
+		fmt.Fprintf(b, "| %s | `%s` | %s | %s | `%s` |\n", drift.Kind, drift.Value, drift.ChainName, escapeCell(drift.Case), drift.ResultID)
+	}
+	b.WriteString("\n")
+}
+
+// writeGas lists the helper gas of each chain and the gas regressions of the run
+func writeGas(b *strings.Builder, helpers []monitor.HelperGasSummary, regressions []monitor.GasRegression) {
+	if len(helpers) == 0 && len(regressions) == 0 {
+		return
+	}
+
+	b.WriteString("### Gas\n\n")
+	if len(helpers) > 0 {
+		b.WriteString("| Chain | Helper | Median helper gas | Max | Samples |\n")
+		b.WriteString("|---|---|---|---|---|\n")
+		for _, helper := range helpers {
+			fmt.Fprintf(b, "| %s | `%s` | %d | %d | %d |\n", helper.ChainName, helper.Helper, helper.Median, helper.Max, helper.Samples)
+		}
+		b.WriteString("\n")
+	}
+	for _, regression := range regressions {
+		fmt.Fprintf(b, "- ⛽ %s\n", regression.String())
+	}
+	if len(regressions) > 0 {
+		b.WriteString("\n")
+	}
+}
+
+// writeDeployments lists the helper deployments with problems
+func writeDeployments(b *strings.Builder, checks []monitor.DeploymentCheck) {
+	var rows []string
+	for _, check := range checks {
+		for _, problem := range check.Problems {
+			rows = append(rows, fmt.Sprintf("| %s | %s | `%s` | %s |\n", check.ChainName, check.Label, check.Address, escapeCell(problem)))
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	b.WriteString("### Helper deployment problems\n\n")
+	b.WriteString("| Chain | Helper | Address | Problem |\n")
+	b.WriteString("|---|---|---|---|\n")
+	for _, row := range rows {
+		b.WriteString(row)
+	}
+	b.WriteString("\n")
+}
+
+// escapeCell keeps a value on one Markdown table row
+func escapeCell(value string) string {
+	value = strings.ReplaceAll(value, "|", "\\|")
+	value = strings.ReplaceAll(value, "\n", " ")
+	if len(value) > 300 {
+		value = value[:300] + "…"
+	}
+	return value
+}
+
+// writeRPCHealth lists the unhealthy RPC endpoints
+func writeRPCHealth(b *strings.Builder, healths []monitor.ChainRPCHealth) {
+	var rows []string
+	for _, health := range healths {
+		for _, endpoint := range health.Endpoints {
+			if !endpoint.Healthy {
+				rows = append(rows, fmt.Sprintf("| %s | %s | %s |\n", health.ChainName, endpoint.Endpoint, escapeCell(endpoint.Error)))
+			}
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	b.WriteString("### Unhealthy RPC endpoints\n\n")
+	b.WriteString("| Chain | Endpoint | Error |\n")
+	b.WriteString("|---|---|---|\n")
+	for _, row := range rows {
+		b.WriteString(row)
+	}
+	b.WriteString("\n")
+}
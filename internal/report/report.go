@@ -0,0 +1,91 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"scale-helper-monitor/internal/monitor"
+)
+
+// Config represents the report output configuration
+type Config struct {
+	JSONFile     string     `mapstructure:"json_file"`
+	JUnitFile    string     `mapstructure:"junit_file"`
+	MarkdownFile string     `mapstructure:"markdown_file"` // Defaults to $GITHUB_STEP_SUMMARY when set
+	ExitPolicy   ExitPolicy `mapstructure:"exit_policy"`
+}
+
+// ExitPolicy decides whether a one-shot run should fail the process
+type ExitPolicy struct {
+	FailOnScaleFailure bool    `mapstructure:"fail_on_scale_failure"`
+	MaxInfraErrorRate  float64 `mapstructure:"max_infra_error_rate"` // Fraction of test cases, 0 disables the check
+}
+
+// Evaluate returns an error when the run violates the policy
+func (p ExitPolicy) Evaluate(run *monitor.RunRecord) error {
+	if p.FailOnScaleFailure && run.Failures > 0 {
+		return fmt.Errorf("%d of %d test cases failed scaling", run.Failures, run.Total)
+	}
+
+	if p.MaxInfraErrorRate > 0 && run.Total > 0 {
+		rate := float64(run.Errors) / float64(run.Total)
+		if rate > p.MaxInfraErrorRate {
+			return fmt.Errorf("infrastructure error rate %.2f%% exceeds %.2f%% (%d of %d test cases)",
+				rate*100, p.MaxInfraErrorRate*100, run.Errors, run.Total)
+		}
+	}
+
+	return nil
+}
+
+// WriteAll writes every configured report for a run
+func (c Config) WriteAll(run *monitor.RunRecord) error {
+	if c.JSONFile != "" {
+		if err := WriteJSON(c.JSONFile, run); err != nil {
+			return err
+		}
+	}
+
+	if c.JUnitFile != "" {
+		if err := WriteJUnit(c.JUnitFile, run); err != nil {
+			return err
+		}
+	}
+
+	markdownFile := c.MarkdownFile
+	if markdownFile == "" {
+		markdownFile = os.Getenv("GITHUB_STEP_SUMMARY")
+	}
+	if markdownFile != "" {
+		if err := WriteMarkdown(markdownFile, run); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes all results of a run as JSON
+func WriteJSON(path string, run *monitor.RunRecord) error {
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON report: %v", err)
+	}
+
+	return writeFile(path, data)
+}
+
+// writeFile writes a report, creating its directory if needed
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create report directory: %v", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write report %s: %v", path, err)
+	}
+
+	return nil
+}
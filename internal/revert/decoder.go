@@ -0,0 +1,238 @@
+package revert
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Reason kinds
+const (
+	KindError   = "error"   // Error(string), raised by require and revert("...")
+	KindPanic   = "panic"   // Panic(uint256), raised by assert, overflows and friends
+	KindCustom  = "custom"  // Custom error found in the registry
+	KindEmpty   = "empty"   // Revert without data
+	KindUnknown = "unknown" // Revert data that matches no known error
+)
+
+var (
+	errorSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+	panicSelector = crypto.Keccak256([]byte("Panic(uint256)"))[:4]
+)
+
+// Reason represents a decoded revert
+type Reason struct {
+	Kind     string `json:"kind"`
+	Message  string `json:"message"`
+	Selector string `json:"selector,omitempty"`
+	Source   string `json:"source,omitempty"` // Registry entry of a custom error, e.g. "uniswap-v4"
+}
+
+// String formats the reason for logs and alerts
+func (r Reason) String() string {
+	switch r.Kind {
+	case KindEmpty:
+		return "reverted without reason"
+	case KindUnknown:
+		return fmt.Sprintf("unknown error %s", r.Message)
+	default:
+		return r.Message
+	}
+}
+
+// registeredError is a custom error together with the registry entry it came from
+type registeredError struct {
+	source string
+	error  abi.Error
+}
+
+// Decoder decodes revert data using a registry of custom error ABIs
+type Decoder struct {
+	errors map[[4]byte]registeredError
+}
+
+// NewDecoder creates a decoder with the built-in registry plus the custom
+// errors of the given ABI JSON files
+func NewDecoder(abiFiles ...string) (*Decoder, error) {
+	d := &Decoder{errors: make(map[[4]byte]registeredError)}
+
+	for _, entry := range builtinABIs {
+		if err := d.RegisterJSON(entry.source, entry.abi); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, path := range abiFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read error ABI %s: %v", path, err)
+		}
+		if err := d.RegisterJSON(path, string(data)); err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
+// RegisterJSON adds the custom errors of an ABI to the registry. Entries that
+// are not errors are ignored, so full contract ABIs can be registered as is.
+func (d *Decoder) RegisterJSON(source, abiJSON string) error {
+	parsed, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		return fmt.Errorf("failed to parse error ABI %s: %v", source, err)
+	}
+
+	for _, abiError := range parsed.Errors {
+		var selector [4]byte
+		copy(selector[:], abiError.ID[:4])
+		if _, exists := d.errors[selector]; !exists {
+			d.errors[selector] = registeredError{source: source, error: abiError}
+		}
+	}
+
+	return nil
+}
+
+// DecodeHex decodes hex encoded revert data
+func (d *Decoder) DecodeHex(data string) Reason {
+	decoded, err := hexutil.Decode(data)
+	if err != nil {
+		if data == "" || data == "0x" {
+			return Reason{Kind: KindEmpty}
+		}
+		return Reason{Kind: KindUnknown, Message: data}
+	}
+	return d.Decode(decoded)
+}
+
+// Decode decodes revert data
+func (d *Decoder) Decode(data []byte) Reason {
+	if len(data) == 0 {
+		return Reason{Kind: KindEmpty}
+	}
+	if len(data) < 4 {
+		return Reason{Kind: KindUnknown, Message: hexutil.Encode(data)}
+	}
+
+	selector := hexutil.Encode(data[:4])
+	switch {
+	case bytes.Equal(data[:4], errorSelector):
+		if message, err := abi.UnpackRevert(data); err == nil {
+			return Reason{Kind: KindError, Message: message, Selector: selector}
+		}
+
+	case bytes.Equal(data[:4], panicSelector):
+		if len(data) == 36 {
+			code := new(big.Int).SetBytes(data[4:])
+			return Reason{Kind: KindPanic, Message: formatPanic(code), Selector: selector}
+		}
+
+	default:
+		var key [4]byte
+		copy(key[:], data[:4])
+		if registered, exists := d.errors[key]; exists {
+			if message, err := d.formatCustom(registered.error, data); err == nil {
+				return Reason{Kind: KindCustom, Message: message, Selector: selector, Source: registered.source}
+			}
+		}
+	}
+
+	return Reason{Kind: KindUnknown, Message: summarize(data), Selector: selector}
+}
+
+// formatCustom renders a custom error with its named arguments, e.g.
+// "PriceLimitAlreadyExceeded(priceCurrent=1, priceLimit=2)"
+func (d *Decoder) formatCustom(abiError abi.Error, data []byte) (string, error) {
+	unpacked, err := abiError.Unpack(data)
+	if err != nil {
+		return "", err
+	}
+	values, ok := unpacked.([]interface{})
+	if !ok {
+		return "", fmt.Errorf("unexpected unpacked type %T", unpacked)
+	}
+
+	args := make([]string, 0, len(values))
+	for i, value := range values {
+		formatted := d.formatValue(value)
+		if name := abiError.Inputs[i].Name; name != "" {
+			formatted = name + "=" + formatted
+		}
+		args = append(args, formatted)
+	}
+
+	return fmt.Sprintf("%s(%s)", abiError.Name, strings.Join(args, ", ")), nil
+}
+
+// formatValue renders an error argument. Nested revert data, as bubbled up by
+// wrapping errors, is decoded recursively.
+func (d *Decoder) formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case []byte:
+		if len(v) >= 4 {
+			if nested := d.Decode(v); nested.Kind != KindUnknown {
+				return nested.String()
+			}
+		}
+		return summarize(v)
+	case [4]byte:
+		return hexutil.Encode(v[:])
+	case [32]byte:
+		return hexutil.Encode(v[:])
+	case common.Address:
+		return v.Hex()
+	case *big.Int:
+		return v.String()
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// FromError extracts revert data from an RPC error, when the node returned any
+func FromError(err error) ([]byte, bool) {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return nil, false
+	}
+
+	data, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return nil, false
+	}
+
+	decoded, decodeErr := hexutil.Decode(data)
+	if decodeErr != nil {
+		return nil, false
+	}
+	return decoded, true
+}
+
+// formatPanic renders a panic code with its meaning
+func formatPanic(code *big.Int) string {
+	if code.IsUint64() {
+		if meaning, exists := panicCodes[code.Uint64()]; exists {
+			return fmt.Sprintf("Panic(0x%02x): %s", code.Uint64(), meaning)
+		}
+	}
+	return fmt.Sprintf("Panic(0x%s)", code.Text(16))
+}
+
+// summarize shortens long revert data for display
+func summarize(data []byte) string {
+	encoded := hex.EncodeToString(data)
+	if len(encoded) > 80 {
+		return fmt.Sprintf("0x%s…%s (%d bytes)", encoded[:40], encoded[len(encoded)-8:], len(data))
+	}
+	return "0x" + encoded
+}
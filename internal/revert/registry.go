@@ -0,0 +1,81 @@
+package revert
+
+// panicCodes maps Panic(uint256) codes to their meaning, see
+// https://docs.soliditylang.org/en/latest/control-structures.html#panic-via-assert-and-error-via-require
+var panicCodes = map[uint64]string{
+	0x00: "generic panic",
+	0x01: "assert(false)",
+	0x11: "arithmetic underflow or overflow",
+	0x12: "division or modulo by zero",
+	0x21: "invalid enum value",
+	0x22: "invalid encoded storage byte array",
+	0x31: "pop() on empty array",
+	0x32: "array index out of bounds",
+	0x41: "out of memory",
+	0x51: "call to zero-initialized internal function",
+}
+
+// builtinABIs holds the custom errors commonly raised along a swap route by
+// the libraries and dexes the router calls. The router reverts with require
+// strings, decoded as Error(string). Executor, scale helper and dex adapter
+// ABIs are added with error_abi_files, from their verified sources.
+var builtinABIs = []struct {
+	source string
+	abi    string
+}{
+	{
+		source: "openzeppelin",
+		abi: `[
+			{"type":"error","name":"ERC20InsufficientBalance","inputs":[{"name":"sender","type":"address"},{"name":"balance","type":"uint256"},{"name":"needed","type":"uint256"}]},
+			{"type":"error","name":"ERC20InsufficientAllowance","inputs":[{"name":"spender","type":"address"},{"name":"allowance","type":"uint256"},{"name":"needed","type":"uint256"}]},
+			{"type":"error","name":"ERC20InvalidSender","inputs":[{"name":"sender","type":"address"}]},
+			{"type":"error","name":"ERC20InvalidReceiver","inputs":[{"name":"receiver","type":"address"}]},
+			{"type":"error","name":"ERC20InvalidApprover","inputs":[{"name":"approver","type":"address"}]},
+			{"type":"error","name":"ERC20InvalidSpender","inputs":[{"name":"spender","type":"address"}]},
+			{"type":"error","name":"SafeERC20FailedOperation","inputs":[{"name":"token","type":"address"}]},
+			{"type":"error","name":"AddressEmptyCode","inputs":[{"name":"target","type":"address"}]},
+			{"type":"error","name":"FailedInnerCall","inputs":[]},
+			{"type":"error","name":"FailedCall","inputs":[]},
+			{"type":"error","name":"ReentrancyGuardReentrantCall","inputs":[]}
+		]`,
+	},
+	{
+		source: "solady",
+		abi: `[
+			{"type":"error","name":"TransferFailed","inputs":[]},
+			{"type":"error","name":"TransferFromFailed","inputs":[]},
+			{"type":"error","name":"ApproveFailed","inputs":[]},
+			{"type":"error","name":"ETHTransferFailed","inputs":[]}
+		]`,
+	},
+	{
+		source: "permit2",
+		abi: `[
+			{"type":"error","name":"AllowanceExpired","inputs":[{"name":"deadline","type":"uint256"}]},
+			{"type":"error","name":"InsufficientAllowance","inputs":[{"name":"amount","type":"uint256"}]},
+			{"type":"error","name":"SignatureExpired","inputs":[{"name":"signatureDeadline","type":"uint256"}]},
+			{"type":"error","name":"InvalidNonce","inputs":[]}
+		]`,
+	},
+	{
+		source: "uniswap-v4",
+		abi: `[
+			{"type":"error","name":"PoolNotInitialized","inputs":[]},
+			{"type":"error","name":"CurrencyNotSettled","inputs":[]},
+			{"type":"error","name":"ManagerLocked","inputs":[]},
+			{"type":"error","name":"AlreadyUnlocked","inputs":[]},
+			{"type":"error","name":"SwapAmountCannotBeZero","inputs":[]},
+			{"type":"error","name":"PriceLimitAlreadyExceeded","inputs":[{"name":"sqrtPriceCurrentX96","type":"uint160"},{"name":"sqrtPriceLimitX96","type":"uint160"}]},
+			{"type":"error","name":"PriceLimitOutOfBounds","inputs":[{"name":"sqrtPriceLimitX96","type":"uint160"}]},
+			{"type":"error","name":"NoLiquidityToReceiveFees","inputs":[]},
+			{"type":"error","name":"InvalidFeeForExactOut","inputs":[]},
+			{"type":"error","name":"NonzeroNativeValue","inputs":[]},
+			{"type":"error","name":"HookNotImplemented","inputs":[]},
+			{"type":"error","name":"InvalidHookResponse","inputs":[]},
+			{"type":"error","name":"HookDeltaExceedsSwapAmount","inputs":[]},
+			{"type":"error","name":"WrappedError","inputs":[{"name":"target","type":"address"},{"name":"selector","type":"bytes4"},{"name":"reason","type":"bytes"},{"name":"details","type":"bytes"}]},
+			{"type":"error","name":"V4TooLittleReceived","inputs":[{"name":"minAmountOutReceived","type":"uint256"},{"name":"amountReceived","type":"uint256"}]},
+			{"type":"error","name":"V4TooMuchRequested","inputs":[{"name":"maxAmountInRequested","type":"uint256"},{"name":"amountRequested","type":"uint256"}]}
+		]`,
+	},
+}
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Storage layouts of a token's balance and allowance mappings
+const (
+	LayoutSolidity = "solidity" // keccak256(key . slot)
+	LayoutVyper    = "vyper"    // keccak256(slot . key)
+	LayoutERC7201  = "erc7201"  // Solidity mappings inside an ERC-7201 namespaced struct
+)
+
+// LegacySlotHolder is the only holder a precomputed balance slot is valid for
+const LegacySlotHolder = "0xdeAD00000000000000000000000000000000dEAd"
+
+// TokenLayout describes where a token keeps balances and allowances
+type TokenLayout struct {
+	Layout        string // LayoutSolidity when empty
+	Namespace     string // ERC-7201 namespace id, e.g. openzeppelin.storage.ERC20
+	BalanceSlot   string // Base slot of balanceOf, an offset within the namespace for erc7201
+	AllowanceSlot string // Base slot of allowance, the allowance is approved in the bundle when empty
+	Slot          string // Precomputed balance slot of LegacySlotHolder, used when BalanceSlot is empty
+}
+
+// Validate checks the layout name and base slots
+func (l TokenLayout) Validate() error {
+	switch l.Layout {
+	case "", LayoutSolidity, LayoutVyper:
+	case LayoutERC7201:
+		if l.Namespace == "" {
+			return fmt.Errorf("erc7201 layout requires a namespace")
+		}
+	default:
+		return fmt.Errorf("unknown storage layout %q", l.Layout)
+	}
+
+	if l.BalanceSlot == "" && l.Slot == "" {
+		return fmt.Errorf("no balance slot")
+	}
+	for _, slot := range []string{l.BalanceSlot, l.AllowanceSlot} {
+		if slot == "" {
+			continue
+		}
+		if _, err := parseSlot(slot); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BalanceOf returns the storage slot of holder's balance
+func (l TokenLayout) BalanceOf(holder string) (string, error) {
+	if l.BalanceSlot == "" {
+		if l.Slot == "" {
+			return "", fmt.Errorf("no balance slot configured")
+		}
+		if !strings.EqualFold(holder, LegacySlotHolder) {
+			return "", fmt.Errorf("precomputed slot is only valid for %s, configure balance_slot to use %s", LegacySlotHolder, holder)
+		}
+		return l.Slot, nil
+	}
+
+	base, err := l.base(l.BalanceSlot)
+	if err != nil {
+		return "", err
+	}
+	return MappingSlot(l.mappingLayout(), addressKey(holder), base).Hex(), nil
+}
+
+// AllowanceOf returns the storage slot of owner's allowance to spender, or an
+// empty string when the allowance slot is unknown
+func (l TokenLayout) AllowanceOf(owner, spender string) (string, error) {
+	if l.AllowanceSlot == "" {
+		return "", nil
+	}
+
+	base, err := l.base(l.AllowanceSlot)
+	if err != nil {
+		return "", err
+	}
+	inner := MappingSlot(l.mappingLayout(), addressKey(owner), base)
+	return MappingSlot(l.mappingLayout(), addressKey(spender), inner).Hex(), nil
+}
+
+// base resolves a configured base slot, adding the namespace root for erc7201
+func (l TokenLayout) base(slot string) (common.Hash, error) {
+	value, err := parseSlot(slot)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if l.Layout == LayoutERC7201 {
+		value.Add(value, NamespaceSlot(l.Namespace).Big())
+	}
+	return common.BigToHash(value), nil
+}
+
+func (l TokenLayout) mappingLayout() string {
+	if l.Layout == LayoutVyper {
+		return LayoutVyper
+	}
+	return LayoutSolidity
+}
+
+// MappingSlot computes the storage slot of key in a mapping stored at slot
+func MappingSlot(layout string, key, slot common.Hash) common.Hash {
+	if layout == LayoutVyper {
+		return crypto.Keccak256Hash(slot.Bytes(), key.Bytes())
+	}
+	return crypto.Keccak256Hash(key.Bytes(), slot.Bytes())
+}
+
+// NamespaceSlot computes the ERC-7201 root slot of a namespace:
+// keccak256(abi.encode(uint256(keccak256(id)) - 1)) & ~bytes32(uint256(0xff))
+func NamespaceSlot(namespace string) common.Hash {
+	id := crypto.Keccak256Hash([]byte(namespace)).Big()
+	id.Sub(id, big.NewInt(1))
+	root := crypto.Keccak256Hash(common.BigToHash(id).Bytes())
+	root[common.HashLength-1] = 0
+	return root
+}
+
+// parseSlot parses a decimal index or a 0x-prefixed slot
+func parseSlot(slot string) (*big.Int, error) {
+	value, ok := new(big.Int).SetString(slot, 0)
+	if !ok || value.Sign() < 0 {
+		return nil, fmt.Errorf("invalid storage slot %q", slot)
+	}
+	return value, nil
+}
+
+func addressKey(address string) common.Hash {
+	return common.BytesToHash(common.HexToAddress(address).Bytes())
+}